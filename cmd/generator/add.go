@@ -3,8 +3,13 @@ package main
 import (
 	"archive/tar"
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
 	"time"
 
 	"github.com/blang/semver"
@@ -12,21 +17,22 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/output"
 )
 
 func init() {
 	generatorCmd.AddCommand(addCmd)
 
-	addCmd.Flags().Bool("beta", false, "Mark release as Beta")
-	addCmd.Flags().Bool("experimental", false, "Mark release as Experimental")
-	addCmd.Flags().Bool("official", false, "Mark this plugin as maintained by Mattermost")
-	addCmd.Flags().Bool("partner", false, "Mark this plugin as maintained by a Mattermost partner")
-	addCmd.Flags().Bool("community", false, "Mark this plugin as maintained by the Open Source Community")
-	addCmd.Flags().Bool("enterprise", false, "Mark this plugin as only available to installations with an E20-only plugins license")
-	addCmd.Flags().Bool("cloud", false, "Mark this plugin as only available to cloud installations")
-	addCmd.Flags().Bool("on-prem", false, "Mark this plugin as only available to on-prem installations")
+	addMetadataFlags(addCmd.Flags())
+	addCmd.Flags().Bool("replace", false, "Replace an existing release with the same version or download URL instead of refusing to add it")
+	addCmd.Flags().Bool("reclassify", false, "Allow changing the author type or hosting of the previous release of the plugin")
+	addCmd.Flags().String("bundle", "", "Add the release from this local plugin bundle instead of downloading it.")
+	addCmd.Flags().String("signature", "", "Path to the signature of the local plugin bundle. Defaults to the bundle path with a .sig suffix.")
+	addCmd.Flags().String("download-url", "", "URL from which the local plugin bundle will be served.")
+	addCmd.MarkFlagsRequiredTogether("bundle", "download-url")
 }
 
 var addCmd = &cobra.Command{
@@ -34,184 +40,510 @@ var addCmd = &cobra.Command{
 	Short: "Add a plugin release to the plugins.json database",
 	Long: "The generator commands allows adding a specific plugin release to the database by using this command.\n\n" +
 		"The release has to be built first using the /mb cutplugin command, which also uploads it to " + defaultRemotePluginStore + "/. " +
-		"This location is used to fetch the plugin release.",
-	Example: `  generator add matterpoll v1.5.1`,
-	Args:    cobra.ExactArgs(2),
+		"This location is used to fetch the plugin release.\n\n" +
+		"Alternatively, a release built elsewhere can be added from a local bundle with --bundle, recording the given --download-url " +
+		"without fetching anything. The signature is read from --signature, defaulting to the bundle path with a .sig suffix.\n\n" +
+		"The author type, hosting, enterprise flag and release stage default to those of the most recent existing release of the plugin. " +
+		"Any metadata differing from that release is logged, and changing the author type or hosting requires --reclassify.\n\n" +
+		"A release whose version or download URL is already in the database is refused, unless --replace is given to update it in place.",
+	Example: `  generator add matterpoll v1.5.1
+  generator add --bundle dist/com.example.plugin-1.0.0.tar.gz --download-url https://example.com/com.example.plugin-1.0.0.tar.gz`,
+	Args: func(command *cobra.Command, args []string) error {
+		// A local bundle identifies the release by its manifest, leaving the repository optional.
+		if command.Flags().Changed("bundle") {
+			return cobra.MaximumNArgs(1)(command, args)
+		}
+
+		return cobra.ExactArgs(2)(command, args)
+	},
 	RunE: func(command *cobra.Command, args []string) error {
 		command.SilenceUsage = true
 
-		official, err := command.Flags().GetBool("official")
+		metadata, err := parseMetadataFlags(command)
 		if err != nil {
 			return err
 		}
 
-		partner, err := command.Flags().GetBool("partner")
+		replace, err := command.Flags().GetBool("replace")
 		if err != nil {
 			return err
 		}
 
-		community, err := command.Flags().GetBool("community")
+		dbFile, err := command.Flags().GetString("database")
 		if err != nil {
 			return err
 		}
 
-		enterprise, err := command.Flags().GetBool("enterprise")
+		plugins, err := pluginsFromDatabase(dbFile)
 		if err != nil {
-			return err
-		}
-
-		if !((official && !partner && !community) ||
-			(!official && partner && !community) ||
-			(!official && !partner && community)) {
-			return errors.New("you must either set the release as a official or as a partner or as a community plugin")
+			return errors.Wrap(err, "failed to read plugins from database")
 		}
 
-		cloud, err := command.Flags().GetBool("cloud")
+		bundlePath, err := command.Flags().GetString("bundle")
 		if err != nil {
 			return err
 		}
 
-		onPrem, err := command.Flags().GetBool("on-prem")
-		if err != nil {
-			return err
+		var plugin *model.Plugin
+		if command.Flags().Changed("bundle") {
+			plugin, err = localReleasePlugin(command, bundlePath)
+			if err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				plugin.RepoName = args[0]
+			}
+		} else {
+			plugin, err = remoteReleasePlugin(command, args[0], args[1])
+			if err != nil {
+				return err
+			}
 		}
 
-		if cloud && onPrem {
-			return errors.New("if you want to make a plugin available for cloud and on-prem, just drop both flags")
+		// Refuse duplicates before previewing any metadata changes.
+		duplicates := duplicateReleases(plugins, plugin)
+		if len(duplicates) > 0 && !replace {
+			existing := duplicates[0]
+			return errors.Errorf("release %s of plugin %s at %s duplicates the existing release %s of plugin %s at %s, use --replace to update it", plugin.Manifest.Version, plugin.Manifest.Id, plugin.DownloadURL, existing.Manifest.Version, existing.Manifest.Id, existing.DownloadURL)
 		}
 
-		beta, err := command.Flags().GetBool("beta")
+		previous := previousRelease(plugins, plugin.Manifest.Id, plugin.Manifest.Version)
+		err = metadata.applyTo(plugin, previous)
 		if err != nil {
 			return err
 		}
 
-		experimental, err := command.Flags().GetBool("experimental")
+		err = writeMetadataChanges(command.OutOrStdout(), previous, plugin)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "failed to write metadata changes")
 		}
 
-		if beta && experimental {
-			return errors.New("can't set the release as both beta and experimental")
-		}
+		if len(duplicates) > 0 {
+			for _, duplicate := range duplicates {
+				logger.Infof("Replacing release %s of plugin %s at %s", duplicate.Manifest.Version, duplicate.Manifest.Id, duplicate.DownloadURL)
+			}
 
-		dbFile, err := command.Flags().GetString("database")
-		if err != nil {
-			return err
+			plugins = slices.DeleteFunc(plugins, func(p *model.Plugin) bool {
+				return slices.Contains(duplicates, p)
+			})
 		}
 
-		plugins, err := pluginsFromDatabase(dbFile)
+		plugins = append(plugins, plugin)
+
+		err = saveDatabase(command, dbFile, plugins)
 		if err != nil {
-			return errors.Wrap(err, "failed to read plugins from database")
+			return errors.Wrap(err, "failed to write plugins database")
 		}
 
-		repo := args[0]
-		tag := args[1]
+		return nil
+	},
+}
+
+// addMetadataFlags registers the flags setting the metadata of a release, as read by
+// parseMetadataFlags.
+func addMetadataFlags(flags *pflag.FlagSet) {
+	flags.Bool("production", false, "Mark release as Production")
+	flags.Bool("beta", false, "Mark release as Beta")
+	flags.Bool("experimental", false, "Mark release as Experimental")
+	flags.Bool("official", false, "Mark this plugin as maintained by Mattermost")
+	flags.Bool("partner", false, "Mark this plugin as maintained by a Mattermost partner")
+	flags.Bool("community", false, "Mark this plugin as maintained by the Open Source Community")
+	flags.Bool("enterprise", false, "Mark this plugin as only available to installations with an E20-only plugins license")
+	flags.Bool("cloud", false, "Mark this plugin as only available to cloud installations")
+	flags.Bool("on-prem", false, "Mark this plugin as only available to on-prem installations")
+}
 
-		if _, err = semver.ParseTolerant(tag); err != nil {
-			return errors.Wrapf(err, "%v is an invalid tag. Something like v2.3.4 is expected", tag)
-		}
+// metadataFlags is the release metadata given on the command line. Fields left nil were not
+// given, and default to the previous release of the plugin.
+type metadataFlags struct {
+	authorType   *model.AuthorType
+	hosting      *model.HostingType
+	enterprise   *bool
+	releaseStage *model.ReleaseStage
+	reclassify   bool
+}
 
-		pluginHost, err := command.Flags().GetString("remote-plugin-store")
-		if err != nil {
-			return err
+// parseMetadataFlags reads the release metadata flags of the given command, rejecting conflicting
+// combinations.
+func parseMetadataFlags(command *cobra.Command) (*metadataFlags, error) {
+	flags := command.Flags()
+	metadata := &metadataFlags{}
+
+	official, err := flags.GetBool("official")
+	if err != nil {
+		return nil, err
+	}
+
+	partner, err := flags.GetBool("partner")
+	if err != nil {
+		return nil, err
+	}
+
+	community, err := flags.GetBool("community")
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.Changed("official") || flags.Changed("partner") || flags.Changed("community") {
+		var authorType model.AuthorType
+		switch {
+		case official && !partner && !community:
+			authorType = model.Mattermost
+		case !official && partner && !community:
+			authorType = model.Partner
+		case !official && !partner && community:
+			authorType = model.Community
+		default:
+			return nil, errors.New("you must either set the release as a official or as a partner or as a community plugin")
 		}
+		metadata.authorType = &authorType
+	}
 
-		bundleURL := fmt.Sprintf("%s/%s-%s.tar.gz", pluginHost, repo, tag)
-		signatureURL := bundleURL + ".sig"
+	cloud, err := flags.GetBool("cloud")
+	if err != nil {
+		return nil, err
+	}
 
-		bundleData, err := downloadBundleData(bundleURL)
-		if err != nil {
-			return errors.Wrapf(err, "failed downloading bundle data")
-		}
+	onPrem, err := flags.GetBool("on-prem")
+	if err != nil {
+		return nil, err
+	}
 
-		manifestData, err := getFromTarFile(tar.NewReader(bytes.NewReader(bundleData)), "plugin.json")
-		if err != nil {
-			return errors.Wrap(err, "failed to read manifest from plugin bundle for release")
-		}
+	if cloud && onPrem {
+		return nil, errors.New("if you want to make a plugin available for cloud and on-prem, just drop both flags")
+	}
 
-		var manifest mattermostModel.Manifest
-		err = json.Unmarshal(manifestData, &manifest)
-		if err != nil {
-			return errors.Wrap(err, "failed to read manifest from plugin bundle for release")
+	if flags.Changed("cloud") || flags.Changed("on-prem") {
+		var hosting model.HostingType
+		switch {
+		case cloud:
+			hosting = model.Cloud
+		case onPrem:
+			hosting = model.OnPrem
 		}
-
-		err = manifest.IsValid()
-		if err != nil {
-			logger.WithFields(logrus.Fields{
-				"id":      manifest.Id,
-				"version": manifest.Version,
-			}).Warn("Plugin manifest is invalid. Double check that the plugin correctly works.")
+		metadata.hosting = &hosting
+	}
+
+	enterprise, err := flags.GetBool("enterprise")
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.Changed("enterprise") {
+		metadata.enterprise = &enterprise
+	}
+
+	production, err := flags.GetBool("production")
+	if err != nil {
+		return nil, err
+	}
+
+	beta, err := flags.GetBool("beta")
+	if err != nil {
+		return nil, err
+	}
+
+	experimental, err := flags.GetBool("experimental")
+	if err != nil {
+		return nil, err
+	}
+
+	if (production && beta) || (production && experimental) || (beta && experimental) {
+		return nil, errors.New("can't set the release as more than one of production, beta and experimental")
+	}
+
+	if flags.Changed("production") || flags.Changed("beta") || flags.Changed("experimental") {
+		releaseStage := model.Production
+		switch {
+		case beta:
+			releaseStage = model.Beta
+		case experimental:
+			releaseStage = model.Experimental
 		}
+		metadata.releaseStage = &releaseStage
+	}
 
-		var iconData string
-		if manifest.IconPath != "" {
-			iconData, err = getIconDataFromTarFile(bundleData, manifest.IconPath)
-			if err != nil {
-				return errors.Wrap(err, "failed to get icon")
-			}
+	metadata.reclassify, err = flags.GetBool("reclassify")
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// applyTo sets the metadata of the given plugin, defaulting anything not given on the command
+// line to the given previous release, if any.
+//
+// Changing the author type or hosting of a plugin requires --reclassify, since it silently changes
+// how the plugin is labeled.
+func (metadata *metadataFlags) applyTo(plugin, previous *model.Plugin) error {
+	if previous == nil && metadata.authorType == nil {
+		return errors.New("you must either set the release as a official or as a partner or as a community plugin")
+	}
+
+	plugin.ReleaseStage = model.Production
+	if previous != nil {
+		plugin.AuthorType = previous.AuthorType
+		plugin.Hosting = previous.Hosting
+		plugin.Enterprise = previous.Enterprise
+		plugin.ReleaseStage = previous.ReleaseStage
+	}
+
+	if metadata.authorType != nil {
+		plugin.AuthorType = *metadata.authorType
+	}
+	if metadata.hosting != nil {
+		plugin.Hosting = *metadata.hosting
+	}
+	if metadata.enterprise != nil {
+		plugin.Enterprise = *metadata.enterprise
+	}
+	if metadata.releaseStage != nil {
+		plugin.ReleaseStage = *metadata.releaseStage
+	}
+
+	if previous == nil || metadata.reclassify {
+		return nil
+	}
+
+	if plugin.AuthorType != previous.AuthorType {
+		return errors.Errorf("author type would change from %q to %q since release %s, use --reclassify to confirm", previous.AuthorType, plugin.AuthorType, previous.Manifest.Version)
+	}
+
+	if plugin.Hosting != previous.Hosting {
+		return errors.Errorf("hosting would change from %q to %q since release %s, use --reclassify to confirm", previous.Hosting, plugin.Hosting, previous.Manifest.Version)
+	}
+
+	return nil
+}
+
+// inheritedFields are the releaseFields a new release inherits from the previous release of the plugin.
+var inheritedFields = []string{"author_type", "hosting", "enterprise", "release_stage"}
+
+// writeMetadataChanges writes the inherited fields of the given plugin differing from the given
+// previous release, if any, from the old to the new value.
+func writeMetadataChanges(w io.Writer, previous, plugin *model.Plugin) error {
+	if previous == nil {
+		return nil
+	}
+
+	var changes []fieldChange
+	for _, field := range releaseFields {
+		if !slices.Contains(inheritedFields, field.name) {
+			continue
 		}
 
-		signature, err := downloadSignature(signatureURL)
-		if err != nil {
-			return errors.Wrap(err, "failed to download plugin signature")
+		oldValue, newValue := field.value(previous), field.value(plugin)
+		if oldValue != newValue {
+			changes = append(changes, fieldChange{Field: field.name, Old: oldValue, New: newValue})
 		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "Metadata of release %s of plugin %s differs from release %s:\n", plugin.Manifest.Version, plugin.Manifest.Id, previous.Manifest.Version)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tOLD\tNEW")
+	for _, change := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", change.Field, output.OrDash(change.Old), output.OrDash(change.New))
+	}
 
-		labels := []model.Label{}
+	return tw.Flush()
+}
+
+// previousRelease returns the release of the plugin with the given id preceding the given version,
+// i.e. the one with the closest lower version, so that a backport inherits the metadata of its own
+// release line. A release of the same version, as replaced by --replace, takes precedence, and without
+// a lower version, it returns the release with the closest higher version, or nil if the plugin has no
+// releases.
+func previousRelease(plugins []*model.Plugin, id, version string) *model.Plugin {
+	target, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil
+	}
 
-		plugin := &model.Plugin{
-			RepoName:        repo,
-			HomepageURL:     manifest.HomepageURL,
-			IconData:        iconData,
-			DownloadURL:     bundleURL,
-			ReleaseNotesURL: manifest.ReleaseNotesURL,
-			Labels:          labels,
-			Signature:       signature,
-			Manifest:        &manifest,
-			Enterprise:      enterprise,
-			UpdatedAt:       time.Now().In(time.UTC),
+	var lower, higher *model.Plugin
+	var lowerVersion, higherVersion semver.Version
+	for _, plugin := range plugins {
+		if plugin.Manifest == nil || plugin.Manifest.Id != id {
+			continue
 		}
 
-		plugin, err = addPlatformSpecificBundles(plugin, pluginHost)
+		pluginVersion, err := semver.ParseTolerant(plugin.Manifest.Version)
 		if err != nil {
-			return err
+			continue
 		}
 
 		switch {
-		case cloud:
-			plugin.Hosting = model.Cloud
-		case onPrem:
-			plugin.Hosting = model.OnPrem
+		case pluginVersion.EQ(target):
+			return plugin
+		case pluginVersion.LT(target):
+			if lower == nil || pluginVersion.GT(lowerVersion) {
+				lower, lowerVersion = plugin, pluginVersion
+			}
+		case pluginVersion.GT(target):
+			if higher == nil || pluginVersion.LT(higherVersion) {
+				higher, higherVersion = plugin, pluginVersion
+			}
 		}
+	}
 
-		switch {
-		case beta:
-			plugin.ReleaseStage = model.Beta
-		case experimental:
-			plugin.ReleaseStage = model.Experimental
-		default:
-			plugin.ReleaseStage = model.Production
-		}
+	if lower != nil {
+		return lower
+	}
 
-		switch {
-		case partner:
-			plugin.AuthorType = model.Partner
-		case community:
-			plugin.AuthorType = model.Community
-		default:
-			plugin.AuthorType = model.Mattermost
-		}
+	return higher
+}
 
-		if enterprise {
-			plugin.Enterprise = true
+// duplicateReleases returns the plugins sharing the id and version, or the download URL, of the
+// given plugin.
+func duplicateReleases(plugins []*model.Plugin, plugin *model.Plugin) []*model.Plugin {
+	var duplicates []*model.Plugin
+	for _, p := range plugins {
+		if p.DownloadURL == plugin.DownloadURL ||
+			(p.Manifest.Id == plugin.Manifest.Id && p.Manifest.Version == plugin.Manifest.Version) {
+			duplicates = append(duplicates, p)
 		}
+	}
 
-		plugins = append(plugins, plugin)
+	return duplicates
+}
+
+// remoteReleasePlugin builds the entry for the given release from the bundle and signature
+// uploaded to the remote plugin store.
+func remoteReleasePlugin(command *cobra.Command, repo, tag string) (*model.Plugin, error) {
+	if command.Flags().Changed("signature") {
+		return nil, errors.New("--signature may only be used with --bundle")
+	}
+
+	if _, err := semver.ParseTolerant(tag); err != nil {
+		return nil, errors.Wrapf(err, "%v is an invalid tag. Something like v2.3.4 is expected", tag)
+	}
+
+	pluginHost, err := command.Flags().GetString("remote-plugin-store")
+	if err != nil {
+		return nil, err
+	}
+
+	bundleURL := fmt.Sprintf("%s/%s-%s.tar.gz", pluginHost, repo, tag)
+	signatureURL := bundleURL + ".sig"
+
+	gzBundleData, err := downloadBundle(bundleURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed downloading bundle data")
+	}
+
+	signature, err := downloadSignature(signatureURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download plugin signature")
+	}
+
+	if err = verifier.verify(gzBundleData, signature); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify signature of %s", bundleURL)
+	}
+
+	bundleData, err := decompressBundle(gzBundleData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress plugin bundle %s", bundleURL)
+	}
+
+	plugin, err := pluginFromBundle(bundleData, bundleURL, signature)
+	if err != nil {
+		return nil, err
+	}
+	plugin.RepoName = repo
+	plugin.SHA256, plugin.Size = checksumBundle(gzBundleData)
+
+	return addPlatformSpecificBundles(plugin, pluginHost)
+}
 
-		err = pluginsToDatabase(dbFile, plugins)
+// localReleasePlugin builds the entry for a release from a local bundle and signature, without
+// any network access. Platform-specific bundles are not probed for.
+func localReleasePlugin(command *cobra.Command, bundlePath string) (*model.Plugin, error) {
+	downloadURL, err := command.Flags().GetString("download-url")
+	if err != nil {
+		return nil, err
+	}
+
+	signaturePath, err := command.Flags().GetString("signature")
+	if err != nil {
+		return nil, err
+	}
+	if signaturePath == "" {
+		signaturePath = bundlePath + ".sig"
+	}
+
+	gzBundleData, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read plugin bundle %s", bundlePath)
+	}
+
+	bundleData, err := decompressBundle(gzBundleData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress plugin bundle %s", bundlePath)
+	}
+
+	signatureData, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read plugin signature %s", signaturePath)
+	}
+	signature := base64.StdEncoding.EncodeToString(signatureData)
+
+	if err = verifier.verify(gzBundleData, signature); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify signature of plugin bundle %s", bundlePath)
+	}
+
+	plugin, err := pluginFromBundle(bundleData, downloadURL, signature)
+	if err != nil {
+		return nil, err
+	}
+	plugin.SHA256, plugin.Size = checksumBundle(gzBundleData)
+
+	return plugin, nil
+}
+
+// pluginFromBundle builds the entry for a release from its uncompressed bundle, reading the
+// manifest and icon.
+func pluginFromBundle(bundleData []byte, downloadURL, signature string) (*model.Plugin, error) {
+	manifestData, err := getFromTarFile(tar.NewReader(bytes.NewReader(bundleData)), "plugin.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest from plugin bundle for release")
+	}
+
+	var manifest mattermostModel.Manifest
+	err = json.Unmarshal(manifestData, &manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest from plugin bundle for release")
+	}
+
+	err = manifest.IsValid()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"id":      manifest.Id,
+			"version": manifest.Version,
+		}).Warn("Plugin manifest is invalid. Double check that the plugin correctly works.")
+	}
+
+	var iconData string
+	if manifest.IconPath != "" {
+		iconData, err = getIconDataFromTarFile(bundleData, manifest.IconPath)
 		if err != nil {
-			return errors.Wrap(err, "failed to write plugins database")
+			return nil, errors.Wrap(err, "failed to get icon")
 		}
-
-		return nil
-	},
+	}
+
+	return &model.Plugin{
+		HomepageURL:     manifest.HomepageURL,
+		IconData:        iconData,
+		DownloadURL:     downloadURL,
+		ReleaseNotesURL: manifest.ReleaseNotesURL,
+		Labels:          []model.Label{},
+		Signature:       signature,
+		Manifest:        &manifest,
+		UpdatedAt:       time.Now().In(time.UTC),
+	}, nil
 }
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestAdd(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0.tar.gz", "mattermost-plugin-demo-v0.2.0.tar.gz")
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	dbFile := filepath.Join(t.TempDir(), "plugins.json")
+	require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("com.example.manual", "1.0.0")}))
+
+	t.Run("invalid tag", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "latest", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+		require.Error(t, err)
+	})
+
+	t.Run("missing bundle", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.3.0", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+		require.Error(t, err)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--official", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--dry-run")
+		require.NoError(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+	})
+
+	t.Run("add release", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--official", "--beta", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+		require.NoError(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 2)
+
+		plugin := plugins[1]
+		require.Equal(t, "com.mattermost.demo-plugin", plugin.Manifest.Id)
+		assert.Equal(t, "0.2.0", plugin.Manifest.Version)
+		assert.Equal(t, "mattermost-plugin-demo", plugin.RepoName)
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0.tar.gz", plugin.DownloadURL)
+		assert.NotEmpty(t, plugin.Signature)
+		assert.NotEmpty(t, plugin.IconData)
+		assert.Equal(t, model.Mattermost, plugin.AuthorType)
+		assert.Equal(t, model.Beta, plugin.ReleaseStage)
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", plugin.Platforms.LinuxAmd64.DownloadURL)
+		assert.Empty(t, plugin.Platforms.WindowsAmd64)
+
+		checksum, size := testdataChecksum(t, "mattermost-plugin-demo-v0.2.0.tar.gz")
+		assert.Equal(t, checksum, plugin.SHA256)
+		assert.Equal(t, size, plugin.Size)
+
+		checksum, size = testdataChecksum(t, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+		assert.Equal(t, checksum, plugin.Platforms.LinuxAmd64.SHA256)
+		assert.Equal(t, size, plugin.Platforms.LinuxAmd64.Size)
+	})
+}
+
+func TestAddLocalBundle(t *testing.T) {
+	_, bundles := setupFakes(t)
+
+	dbFile := filepath.Join(t.TempDir(), "plugins.json")
+	require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+	bundlePath := filepath.Join("testdata", "mattermost-plugin-demo-v0.2.0.tar.gz")
+	downloadURL := "https://private.example.com/demo-0.2.0.tar.gz"
+
+	t.Run("download url is required", func(t *testing.T) {
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--community", "--database", dbFile)
+		require.Error(t, err)
+	})
+
+	t.Run("signature requires a bundle", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--signature", bundlePath+".sig", "--community", "--database", dbFile)
+		require.EqualError(t, err, "--signature may only be used with --bundle")
+	})
+
+	t.Run("too many arguments", func(t *testing.T) {
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.Error(t, err)
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--signature", filepath.Join(t.TempDir(), "missing.sig"), "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.Error(t, err)
+	})
+
+	t.Run("default signature path", func(t *testing.T) {
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+
+		plugin := plugins[0]
+		assert.Equal(t, "com.mattermost.demo-plugin", plugin.Manifest.Id)
+		assert.Equal(t, "0.2.0", plugin.Manifest.Version)
+		assert.Equal(t, downloadURL, plugin.DownloadURL)
+		assert.Empty(t, plugin.RepoName)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("signature of mattermost-plugin-demo-v0.2.0.tar.gz\n")), plugin.Signature)
+		assert.NotEmpty(t, plugin.IconData)
+		assert.Equal(t, model.Community, plugin.AuthorType)
+		assert.Empty(t, plugin.Platforms.LinuxAmd64)
+
+		checksum, size := testdataChecksum(t, "mattermost-plugin-demo-v0.2.0.tar.gz")
+		assert.Equal(t, checksum, plugin.SHA256)
+		assert.Equal(t, size, plugin.Size)
+	})
+
+	t.Run("explicit signature and repository", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+		signaturePath := filepath.Join("testdata", "mattermost-plugin-demo-v0.1.0.tar.gz.sig")
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "--bundle", bundlePath, "--signature", signaturePath, "--download-url", downloadURL, "--official", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+
+		assert.Equal(t, "mattermost-plugin-demo", plugins[0].RepoName)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("signature of mattermost-plugin-demo-v0.1.0.tar.gz\n")), plugins[0].Signature)
+	})
+
+	assert.Empty(t, bundles.requests, "a local bundle must be added without network access")
+}
+
+func TestAddInheritsMetadata(t *testing.T) {
+	setupFakes(t)
+
+	bundlePath := filepath.Join("testdata", "mattermost-plugin-demo-v0.2.0.tar.gz")
+	downloadURL := "https://private.example.com/demo-0.2.0.tar.gz"
+
+	// setupDatabase writes a database holding releases of the demo plugin, the most recent of
+	// which is a partner plugin limited to cloud installations.
+	setupDatabase := func(t *testing.T) string {
+		t.Helper()
+
+		older := makePlugin("com.mattermost.demo-plugin", "0.0.9")
+		older.AuthorType = model.Mattermost
+		older.ReleaseStage = model.Production
+
+		previous := makePlugin("com.mattermost.demo-plugin", "0.1.0")
+		previous.AuthorType = model.Partner
+		previous.Hosting = model.Cloud
+		previous.Enterprise = true
+		previous.ReleaseStage = model.Beta
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{older, previous, makePlugin("com.example.other", "2.0.0")}))
+
+		return dbFile
+	}
+
+	// addedRelease returns the release added to the given database.
+	addedRelease := func(t *testing.T, dbFile string) *model.Plugin {
+		t.Helper()
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+
+		index := slices.IndexFunc(plugins, func(plugin *model.Plugin) bool {
+			return matchesRelease(plugin, "com.mattermost.demo-plugin", "0.2.0")
+		})
+		require.NotEqual(t, -1, index)
+
+		return plugins[index]
+	}
+
+	t.Run("new plugin requires an author type", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("com.example.other", "2.0.0")}))
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--database", dbFile)
+		require.EqualError(t, err, "you must either set the release as a official or as a partner or as a community plugin")
+	})
+
+	t.Run("defaults to the most recent release", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--database", dbFile)
+		require.NoError(t, err)
+
+		plugin := addedRelease(t, dbFile)
+		assert.Equal(t, model.Partner, plugin.AuthorType)
+		assert.Equal(t, model.Cloud, plugin.Hosting)
+		assert.True(t, plugin.Enterprise)
+		assert.Equal(t, model.Beta, plugin.ReleaseStage)
+	})
+
+	t.Run("flags override the release stage and enterprise flag", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--production", "--enterprise=false", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugin := addedRelease(t, dbFile)
+		assert.Equal(t, model.Partner, plugin.AuthorType)
+		assert.False(t, plugin.Enterprise)
+		assert.Equal(t, model.Production, plugin.ReleaseStage)
+	})
+
+	t.Run("changed metadata is printed", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		output := &bytes.Buffer{}
+		generatorCmd.SetOut(output)
+		t.Cleanup(func() {
+			generatorCmd.SetOut(nil)
+		})
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--production", "--enterprise=false", "--database", dbFile)
+		require.NoError(t, err)
+
+		assert.Equal(t, `Metadata of release 0.2.0 of plugin com.mattermost.demo-plugin differs from release 0.1.0:
+FIELD          OLD   NEW
+enterprise     true  false
+release_stage  beta  production
+`, output.String())
+	})
+
+	t.Run("conflicting release stages", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--production", "--beta", "--database", dbFile)
+		require.EqualError(t, err, "can't set the release as more than one of production, beta and experimental")
+	})
+
+	t.Run("changing the author type requires reclassify", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.EqualError(t, err, `author type would change from "partner" to "community" since release 0.1.0, use --reclassify to confirm`)
+
+		err = runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--reclassify", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugin := addedRelease(t, dbFile)
+		assert.Equal(t, model.Community, plugin.AuthorType)
+		assert.Equal(t, model.Cloud, plugin.Hosting)
+	})
+
+	t.Run("changing the hosting requires reclassify", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--cloud=false", "--database", dbFile)
+		require.EqualError(t, err, `hosting would change from "cloud" to "" since release 0.1.0, use --reclassify to confirm`)
+
+		err = runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--cloud=false", "--reclassify", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugin := addedRelease(t, dbFile)
+		assert.Equal(t, model.Partner, plugin.AuthorType)
+		assert.Empty(t, plugin.Hosting)
+	})
+}
+
+func TestAddDuplicates(t *testing.T) {
+	setupFakes(t)
+
+	bundlePath := filepath.Join("testdata", "mattermost-plugin-demo-v0.2.0.tar.gz")
+	downloadURL := "https://private.example.com/demo-0.2.0.tar.gz"
+
+	existing := makePlugin("com.mattermost.demo-plugin", "0.2.0")
+	existing.AuthorType = model.Community
+	existing.ReleaseStage = model.Production
+	other := makePlugin("com.example.other", "1.0.0")
+	other.DownloadURL = downloadURL
+
+	t.Run("same version is refused", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{existing}))
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--database", dbFile)
+		require.EqualError(t, err, "release 0.2.0 of plugin com.mattermost.demo-plugin at "+downloadURL+" duplicates the existing release 0.2.0 of plugin com.mattermost.demo-plugin at "+existing.DownloadURL+", use --replace to update it")
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, existing.DownloadURL, plugins[0].DownloadURL)
+	})
+
+	t.Run("same download url is refused", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{other}))
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.EqualError(t, err, "release 0.2.0 of plugin com.mattermost.demo-plugin at "+downloadURL+" duplicates the existing release 1.0.0 of plugin com.example.other at "+downloadURL+", use --replace to update it")
+	})
+
+	t.Run("duplicates are refused before printing metadata changes", func(t *testing.T) {
+		previous := makePlugin("com.mattermost.demo-plugin", "0.1.0")
+		previous.AuthorType = model.Community
+		previous.ReleaseStage = model.Production
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{previous, other}))
+
+		output := &bytes.Buffer{}
+		generatorCmd.SetOut(output)
+		t.Cleanup(func() {
+			generatorCmd.SetOut(nil)
+		})
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--beta", "--database", dbFile)
+		require.ErrorContains(t, err, "use --replace to update it")
+		assert.Empty(t, output.String())
+	})
+
+	t.Run("replace updates in place", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{existing, other, makePlugin("com.mattermost.demo-plugin", "0.1.0")}))
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--replace", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 2)
+
+		// The other plugin sharing the download URL is replaced as well.
+		plugin := plugins[0]
+		require.Equal(t, "com.mattermost.demo-plugin", plugin.Manifest.Id)
+		assert.Equal(t, "0.2.0", plugin.Manifest.Version)
+		assert.Equal(t, downloadURL, plugin.DownloadURL)
+		assert.Equal(t, model.Community, plugin.AuthorType)
+		assert.Equal(t, "0.1.0", plugins[1].Manifest.Version)
+	})
+
+	t.Run("adding twice is refused", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.NoError(t, err)
+
+		err = runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile)
+		require.Error(t, err)
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+	})
+}
+
+func TestPreviousRelease(t *testing.T) {
+	plugins := []*model.Plugin{
+		makePlugin("com.example.a", "2.0.0"),
+		makePlugin("com.example.a", "1.0.0"),
+		makePlugin("com.example.a", "1.2.0"),
+		makePlugin("com.example.b", "1.2.4"),
+	}
+
+	for _, tc := range []struct {
+		version  string
+		expected string
+	}{
+		{"3.0.0", "2.0.0"},
+		{"1.2.5", "1.2.0"},
+		{"1.2.0", "1.2.0"},
+		{"v1.1.0", "1.0.0"},
+		{"0.9.0", "1.0.0"},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			previous := previousRelease(plugins, "com.example.a", tc.version)
+			require.NotNil(t, previous)
+			assert.Equal(t, tc.expected, previous.Manifest.Version)
+		})
+	}
+
+	assert.Nil(t, previousRelease(plugins, "com.example.c", "1.0.0"))
+}
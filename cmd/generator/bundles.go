@@ -1,31 +1,54 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
+	"strings"
 
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/mattermost/mattermost-marketplace/internal/model"
 )
 
-// OSX-specific bundle URLs are stored in the plugin store as `osx` rather than `darwin`
-const OsxAmd64 = "osx-amd64"
+// remotePlatformName returns the name of the given platform in the file names of bundles on the
+// remote file server, which stores OSX-specific bundles as `osx` rather than `darwin`.
+func remotePlatformName(platform string) string {
+	if arch, ok := strings.CutPrefix(platform, "darwin-"); ok {
+		return "osx-" + arch
+	}
+
+	return platform
+}
 
 func init() {
 	generatorCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().Int("to", model.SchemaVersion, "The schema version to migrate the database to.")
+	migrateCmd.Flags().Int("concurrency", 4, "How many plugins to migrate at once.")
+	migrateCmd.Flags().Bool("backfill-checksums", false, "Download bundles without a recorded checksum to record their checksum and size.")
 }
 
 var migrateCmd = &cobra.Command{
-	Use:     "migrate",
-	Short:   "Migrate existing plugins in plugins.json to the newest structure.",
-	Long:    "The migrate command adds platform-specific bundles to each existing entry.",
-	Example: "generator migrate",
+	Use:   "migrate",
+	Short: "Migrate existing plugins in plugins.json to a newer schema version.",
+	Long: "The migrate command upgrades the database to the schema version given by --to, defaulting to the newest, " +
+		"applying each named migration in between in order.\n\n" +
+		"Entries that fail to migrate, e.g. since a bundle could not be downloaded, are reported and kept unchanged, " +
+		"and the command fails after writing the remaining entries without upgrading the schema version of the database. " +
+		"Running the command again retries the failed entries.\n\n" +
+		"With --backfill-checksums, the default and platform-specific bundles of entries predating checksums are downloaded " +
+		"once to record their checksum and size.",
+	Example: `  generator migrate
+  generator migrate --to 1
+  generator migrate --backfill-checksums`,
 	RunE: func(command *cobra.Command, _ []string) error {
+		command.SilenceUsage = true
+
 		dbFile, err := command.Flags().GetString("database")
 		if err != nil {
 			return err
@@ -36,66 +59,138 @@ var migrateCmd = &cobra.Command{
 			return err
 		}
 
-		existingPlugins, err := pluginsFromDatabase(dbFile)
+		to, _ := command.Flags().GetInt("to")
+		if to > model.SchemaVersion {
+			return errors.Errorf("schema version %d is newer than the supported schema version %d", to, model.SchemaVersion)
+		}
+
+		concurrency, _ := command.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return errors.New("concurrency must be at least 1")
+		}
+
+		backfill, err := command.Flags().GetBool("backfill-checksums")
+		if err != nil {
+			return err
+		}
+
+		database, err := databaseFromFile(dbFile)
 		if err != nil {
 			return errors.Wrap(err, "failed to read plugins from database")
 		}
 
-		var g errgroup.Group
-		toSave := []*model.Plugin{}
-		for _, orig := range existingPlugins {
-			orig := orig
+		from := database.SchemaVersion
+		if to < from {
+			return errors.Errorf("database %s has schema version %d, which cannot be downgraded to %d", dbFile, from, to)
+		}
+		if to == from && !backfill {
+			logger.Infof("Database %s already has schema version %d", dbFile, to)
+			return nil
+		}
 
-			g.Go(func() error {
-				var modified *model.Plugin
-				modified, err = addPlatformSpecificBundles(orig, pluginHost)
-				if err != nil {
-					return errors.Wrapf(err, "failed to add platform-specific bundles for plugin %s-%s", orig.Manifest.Id, orig.Manifest.Version)
-				}
+		for _, migration := range schemaMigrations[from:to] {
+			logger.Infof("Applying migration %s", migration.name)
+		}
 
-				// Migrate community label to flag
-				var newLabels []model.Label
-				for _, l := range modified.Labels {
-					switch l {
-					case model.EnterpriseLabel:
-						// Just drop it
-					case model.CommunityLabel:
-						modified.AuthorType = model.Community
-					case model.BetaLabel:
-						modified.ReleaseStage = model.Beta
-					default:
-						// Keep other labels
-						newLabels = append(newLabels, l)
-					}
-				}
-				modified.Labels = newLabels
+		existingPlugins := database.Plugins
 
-				if modified.AuthorType == "" {
-					modified.AuthorType = model.Mattermost
-				}
+		// Collect the result of each plugin by index to keep the output deterministic.
+		migrated := make([]*model.Plugin, len(existingPlugins))
+		failures := make([]error, len(existingPlugins))
 
-				if modified.ReleaseStage == "" {
-					modified.ReleaseStage = model.Production
+		g := errgroup.Group{}
+		g.SetLimit(concurrency)
+		for i, orig := range existingPlugins {
+			g.Go(func() error {
+				// Migrate a copy, keeping the original should the migration fail halfway.
+				plugin := *orig
+				failures[i] = migrateSchema(&plugin, pluginHost, from, to)
+				if failures[i] == nil && backfill {
+					failures[i] = backfillChecksums(&plugin)
 				}
-
-				toSave = append(toSave, modified)
+				migrated[i] = &plugin
 
 				return nil
 			})
 		}
+		_ = g.Wait()
+
+		failed := 0
+		for i, failure := range failures {
+			if failure == nil {
+				continue
+			}
+
+			orig := existingPlugins[i]
+			logger.WithError(failure).WithFields(logrus.Fields{
+				"id":      orig.Manifest.Id,
+				"version": orig.Manifest.Version,
+			}).Error("Failed to migrate plugin, keeping it unchanged")
+
+			migrated[i] = orig
+			failed++
+		}
 
-		if err = g.Wait(); err != nil {
-			return errors.Wrap(err, "failed to get a migrate a plugin to new structure")
+		// Only upgrade the schema version once every entry is migrated.
+		schemaVersion := to
+		if failed > 0 {
+			schemaVersion = from
 		}
 
-		err = pluginsToDatabase(dbFile, toSave)
+		err = saveDatabaseVersion(command, dbFile, schemaVersion, migrated)
 		if err != nil {
 			return errors.Wrap(err, "failed to write plugins database")
 		}
+
+		if failed > 0 {
+			return errors.Errorf("failed to migrate %d of %d plugins in %s, keeping schema version %d", failed, len(existingPlugins), dbFile, from)
+		}
+
 		return nil
-	}}
+	},
+}
+
+// backfillChecksums records the checksum and size of the default and platform-specific bundles of the
+// given plugin lacking them, verifying the signature of each downloaded bundle.
+func backfillChecksums(plugin *model.Plugin) error {
+	type unchecked struct {
+		downloadURL string
+		signature   string
+		sha256      *string
+		size        *int64
+	}
+
+	bundles := []unchecked{{plugin.DownloadURL, plugin.Signature, &plugin.SHA256, &plugin.Size}}
+	for _, platform := range supportedPlatforms {
+		bundle := platformBundle(&plugin.Platforms, platform)
+		bundles = append(bundles, unchecked{bundle.DownloadURL, bundle.Signature, &bundle.SHA256, &bundle.Size})
+	}
+
+	for _, bundle := range bundles {
+		if bundle.downloadURL == "" || *bundle.sha256 != "" {
+			continue
+		}
+
+		gzBundleData, err := downloadBundle(bundle.downloadURL)
+		if err != nil {
+			return errors.Wrapf(err, "failed to download bundle %s", bundle.downloadURL)
+		}
+
+		// Entries predating signed releases are backfilled without verification.
+		if verifier != nil && bundle.signature != "" {
+			if err = verifier.verify(gzBundleData, bundle.signature); err != nil {
+				return errors.Wrapf(err, "failed to verify signature of %s", bundle.downloadURL)
+			}
+		}
+
+		*bundle.sha256, *bundle.size = checksumBundle(gzBundleData)
+	}
+
+	return nil
+}
 
-// addPlatformSpecificBundles includes the platform-specific bundle URLs and signatures in the Marketplace entries.
+// addPlatformSpecificBundles includes the platform-specific bundle URLs, signatures and checksums in the Marketplace entries,
+// for each platform declared by the server executables of the plugin manifest and published on the remote file server.
 func addPlatformSpecificBundles(plugin *model.Plugin, pluginHost string) (*model.Plugin, error) {
 	if plugin.RepoName == "" {
 		return plugin, nil
@@ -104,79 +199,135 @@ func addPlatformSpecificBundles(plugin *model.Plugin, pluginHost string) (*model
 	repo := plugin.RepoName
 	pluginWithVersion := fmt.Sprintf("%s-v%s", repo, plugin.Manifest.Version)
 
-	platforms, err := checkIfRemoteBundlesExist(pluginHost, pluginWithVersion)
+	platforms, err := reconcileRemoteBundles(pluginHost, pluginWithVersion, declaredPlatforms(plugin.Manifest))
 	if err != nil {
 		return nil, err
 	}
 
+	existing := plugin.Platforms
 	plugin.Platforms = model.PlatformBundles{}
 	for _, platform := range platforms {
-		fname := fmt.Sprintf("%s-%s.tar.gz", pluginWithVersion, platform)
+		fname := fmt.Sprintf("%s-%s.tar.gz", pluginWithVersion, remotePlatformName(platform))
 
 		pluginPath := fmt.Sprintf("%s/%s", pluginHost, fname)
 		sigPath := pluginPath + ".sig"
 
-		res, err := http.Get(sigPath)
+		signatureStr, err := downloadSignature(sigPath)
 		if err != nil {
 			return nil, err
 		}
-		defer res.Body.Close()
 
-		signatureBytes, err := io.ReadAll(res.Body)
+		// Only download new or changed bundles, keeping the checksum of those already recorded.
+		if previous := platformBundle(&existing, platform); previous.DownloadURL == pluginPath && previous.Signature == signatureStr {
+			logger.Debugf("skipping download of unchanged platform-specific bundle %s", pluginPath)
+			*platformBundle(&plugin.Platforms, platform) = *previous
+			continue
+		}
+
+		gzBundleData, err := downloadBundle(pluginPath)
 		if err != nil {
 			return nil, err
 		}
-		signatureStr := base64.StdEncoding.EncodeToString(signatureBytes)
+
+		if err = verifier.verify(gzBundleData, signatureStr); err != nil {
+			return nil, errors.Wrapf(err, "failed to verify signature of %s", pluginPath)
+		}
 
 		bundle := model.PlatformBundleMetadata{
 			DownloadURL: pluginPath,
 			Signature:   signatureStr,
 		}
+		bundle.SHA256, bundle.Size = checksumBundle(gzBundleData)
 
-		switch platform {
-		case model.LinuxAmd64:
-			plugin.Platforms.LinuxAmd64 = bundle
-		case OsxAmd64:
-			plugin.Platforms.DarwinAmd64 = bundle
-		case model.WindowsAmd64:
-			plugin.Platforms.WindowsAmd64 = bundle
-		}
+		*platformBundle(&plugin.Platforms, platform) = bundle
 	}
 
 	return plugin, nil
 }
 
-// checkIfRemoteBundlesExist checks which platform-specific bundles are available on the remote file server, as well as their signatures.
-func checkIfRemoteBundlesExist(remotePluginHost, pluginWithVersion string) ([]string, error) {
-	result := []string{}
+// platformBundle returns the bundle of the given supported platform, or nil for an unsupported platform.
+func platformBundle(bundles *model.PlatformBundles, platform string) *model.PlatformBundleMetadata {
+	switch platform {
+	case model.LinuxAmd64:
+		return &bundles.LinuxAmd64
+	case model.DarwinAmd64:
+		return &bundles.DarwinAmd64
+	case model.WindowsAmd64:
+		return &bundles.WindowsAmd64
+	}
 
-	platforms := []string{model.LinuxAmd64, OsxAmd64, model.WindowsAmd64}
-	for _, platform := range platforms {
-		path := fmt.Sprintf("%s/%s-%s.tar.gz", remotePluginHost, pluginWithVersion, platform)
+	return nil
+}
+
+// supportedPlatforms are the platforms for which the Marketplace entries hold a platform-specific bundle.
+var supportedPlatforms = []string{model.LinuxAmd64, model.DarwinAmd64, model.WindowsAmd64}
+
+// declaredPlatforms returns the platforms declared by the server executables of the given manifest,
+// in sorted order. Plugins without a server, or with a single executable, declare none.
+func declaredPlatforms(manifest *mattermostModel.Manifest) []string {
+	if manifest == nil || manifest.Server == nil {
+		return nil
+	}
 
-		// Check if plugin bundle exists on remote file server
-		res, err := http.Head(path)
+	platforms := make([]string, 0, len(manifest.Server.Executables))
+	for platform := range manifest.Server.Executables {
+		platforms = append(platforms, platform)
+	}
+	slices.Sort(platforms)
+
+	return platforms
+}
+
+// reconcileRemoteBundles checks which of the supported platforms have a platform-specific bundle and signature
+// available on the remote file server, returning those also declared by the manifest.
+//
+// Mismatches are reported in both directions: declared platforms without a published bundle fall back to the
+// default bundle, while published bundles for undeclared platforms are ignored. Declared platforms the Marketplace
+// entries do not support are reported without checking the remote file server.
+func reconcileRemoteBundles(remotePluginHost, pluginWithVersion string, declared []string) ([]string, error) {
+	for _, platform := range declared {
+		if !slices.Contains(supportedPlatforms, platform) {
+			logger.WithFields(logrus.Fields{"bundle": pluginWithVersion, "platform": platform}).Warnf("Manifest declares an executable for %s, but platform-specific bundles for it are not supported", platform)
+		}
+	}
+
+	result := []string{}
+	for _, platform := range supportedPlatforms {
+		path := fmt.Sprintf("%s/%s-%s.tar.gz", remotePluginHost, pluginWithVersion, remotePlatformName(platform))
+
+		published, err := remoteBundleExists(path)
 		if err != nil {
 			return nil, err
 		}
-		if res.StatusCode != http.StatusOK {
-			logger.Debugf("Platform-specific bundle not found %s %s", pluginWithVersion, path)
-			continue
+
+		fields := logrus.Fields{"bundle": pluginWithVersion, "platform": platform}
+		isDeclared := slices.Contains(declared, platform)
+		switch {
+		case isDeclared && !published:
+			logger.WithFields(fields).Warnf("Manifest declares an executable for %s, but no signed bundle is published at %s", platform, path)
+		case !isDeclared && published:
+			logger.WithFields(fields).Warnf("A signed bundle is published at %s, but the manifest declares no executable for %s, ignoring it", path, platform)
+		case isDeclared && published:
+			result = append(result, platform)
 		}
+	}
+
+	return result, nil
+}
 
-		// Check if signature exists on remote file server
-		sigPath := path + ".sig"
-		res, err = http.Head(sigPath)
+// remoteBundleExists checks whether the bundle at the given path is available on the remote file server, as well as
+// its signature.
+func remoteBundleExists(path string) (bool, error) {
+	for _, url := range []string{path, path + ".sig"} {
+		statusCode, _, err := fetch(http.MethodHead, url)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
-		if res.StatusCode != http.StatusOK {
-			logger.Debugf("Platform-specific bundle signature not found %s %s", pluginWithVersion, sigPath)
-			continue
+		if statusCode != http.StatusOK {
+			logger.Debugf("Platform-specific bundle file not found %s", url)
+			return false, nil
 		}
-
-		result = append(result, platform)
 	}
 
-	return result, nil
+	return true, nil
 }
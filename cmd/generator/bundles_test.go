@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestMigrate(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	dbFile := writeDatabase(t, `[
+		{
+			"homepage_url": "https://github.com/mattermost/mattermost-plugin-demo",
+			"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+			"repo_name": "mattermost-plugin-demo",
+			"labels": [{"name": "Community", "description": "This plugin is maintained by the Open Source Community.", "url": "https://mattermost.com/pl/default-community-plugins"}],
+			"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0", "server": {"executables": {"linux-amd64": "server/dist/plugin-linux-amd64"}}},
+			"updated_at": "2026-10-01T12:00:00Z"
+		}
+	]`)
+
+	err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+	require.NoError(t, err)
+
+	plugins, err := pluginsFromDatabase(dbFile)
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+
+	plugin := plugins[0]
+	assert.Equal(t, model.Community, plugin.AuthorType)
+	assert.Equal(t, model.Production, plugin.ReleaseStage)
+	assert.Empty(t, plugin.Labels)
+	assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", plugin.Platforms.LinuxAmd64.DownloadURL)
+	assert.Empty(t, plugin.Platforms.DarwinAmd64)
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	dbFile := writeDatabase(t, `[
+		{
+			"homepage_url": "https://github.com/mattermost/mattermost-plugin-demo",
+			"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+			"repo_name": "mattermost-plugin-demo",
+			"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0", "server": {"executables": {"linux-amd64": "server/dist/plugin-linux-amd64"}}},
+			"updated_at": "2026-10-01T12:00:00Z"
+		}
+	]`)
+	original, err := os.ReadFile(dbFile)
+	require.NoError(t, err)
+
+	t.Run("dry run", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		generatorCmd.SetOut(output)
+		t.Cleanup(func() {
+			generatorCmd.SetOut(nil)
+		})
+
+		err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--dry-run")
+		require.NoError(t, err)
+
+		assert.Contains(t, output.String(), "platforms.linux-amd64.download_url")
+		assert.Contains(t, bundles.requests, "GET "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+		data, err := os.ReadFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, original, data)
+	})
+
+	t.Run("output", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "plugins.json")
+
+		err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--output", outputFile)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, original, data)
+
+		plugins, err := pluginsFromDatabase(outputFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", plugins[0].Platforms.LinuxAmd64.DownloadURL)
+	})
+}
+
+func TestMigrateKeepsFailedPlugins(t *testing.T) {
+	_, bundles := setupFakes(t)
+	signer := newTestSigner(t)
+	publicKey := signer.writePublicKey(t, true)
+
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0.tar.gz", "mattermost-plugin-demo-v0.2.0.tar.gz")
+
+	// The first entry is signed by an unknown key, and so fails to migrate.
+	dbFile := writeDatabase(t, `[
+		{
+			"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+			"signature": "c2lnbmF0dXJl",
+			"repo_name": "mattermost-plugin-demo",
+			"labels": [{"name": "Beta", "description": "This plugin is currently in Beta and is not recommended for use in production.", "url": "https://mattermost.com/pl/default-beta-plugins"}],
+			"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0", "server": {"executables": {"linux-amd64": "server/dist/plugin-linux-amd64"}}},
+			"updated_at": "2026-10-01T12:00:00Z"
+		},
+		{
+			"download_url": "https://example.com/com.example.other-1.0.0.tar.gz",
+			"labels": [{"name": "Community", "description": "This plugin is maintained by the Open Source Community.", "url": "https://mattermost.com/pl/default-community-plugins"}],
+			"manifest": {"id": "com.example.other", "name": "Other", "version": "1.0.0"},
+			"updated_at": "2026-10-01T12:00:00Z"
+		}
+	]`)
+	original, err := databaseFromFile(dbFile)
+	require.NoError(t, err)
+
+	err = runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--public-key", publicKey, "--concurrency", "1")
+	require.EqualError(t, err, "failed to migrate 1 of 2 plugins in "+dbFile+", keeping schema version 0")
+
+	database, err := databaseFromFile(dbFile)
+	require.NoError(t, err)
+	assert.Equal(t, 0, database.SchemaVersion)
+
+	plugins := database.Plugins
+	require.Len(t, plugins, 2)
+
+	// The database is sorted by id, putting the migrated entry first.
+	assert.Equal(t, "com.example.other", plugins[0].Manifest.Id)
+	assert.Equal(t, model.Community, plugins[0].AuthorType)
+	assert.Equal(t, model.Production, plugins[0].ReleaseStage)
+	assert.Empty(t, plugins[0].Labels)
+
+	assert.Equal(t, original.Plugins[0], plugins[1])
+}
+
+func TestMigrateTo(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	dbFile := writeDatabase(t, `[
+		{
+			"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+			"repo_name": "mattermost-plugin-demo",
+			"labels": [{"name": "Beta", "description": "This plugin is currently in Beta and is not recommended for use in production.", "url": "https://mattermost.com/pl/default-beta-plugins"}],
+			"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0", "server": {"executables": {"linux-amd64": "server/dist/plugin-linux-amd64"}}},
+			"updated_at": "2026-10-01T12:00:00Z"
+		}
+	]`)
+
+	t.Run("newer than supported", func(t *testing.T) {
+		err := runGenerator(t, "migrate", "--database", dbFile, "--to", "99")
+		require.EqualError(t, err, "schema version 99 is newer than the supported schema version 2")
+	})
+
+	t.Run("writer commands require the newest schema version", func(t *testing.T) {
+		err := runGenerator(t, "remove", "--database", dbFile, "com.mattermost.demo-plugin", "--all")
+		require.EqualError(t, err, "failed to read plugins from database: database "+dbFile+" has schema version 0, run generator migrate to upgrade it to 2")
+	})
+
+	t.Run("single step", func(t *testing.T) {
+		err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--to", "1")
+		require.NoError(t, err)
+		assert.Empty(t, bundles.requests)
+
+		database, err := databaseFromFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, 1, database.SchemaVersion)
+		require.Len(t, database.Plugins, 1)
+		assert.Equal(t, model.Beta, database.Plugins[0].ReleaseStage)
+		assert.Empty(t, database.Plugins[0].Labels)
+		assert.Empty(t, database.Plugins[0].Platforms)
+	})
+
+	t.Run("downgrade", func(t *testing.T) {
+		err := runGenerator(t, "migrate", "--database", dbFile, "--to", "0")
+		require.EqualError(t, err, "database "+dbFile+" has schema version 1, which cannot be downgraded to 0")
+	})
+
+	t.Run("remaining steps", func(t *testing.T) {
+		err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+		require.NoError(t, err)
+
+		database, err := databaseFromFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, model.SchemaVersion, database.SchemaVersion)
+		require.Len(t, database.Plugins, 1)
+		assert.Equal(t, model.Beta, database.Plugins[0].ReleaseStage)
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", database.Plugins[0].Platforms.LinuxAmd64.DownloadURL)
+	})
+
+	t.Run("already migrated", func(t *testing.T) {
+		original, err := os.ReadFile(dbFile)
+		require.NoError(t, err)
+
+		err = runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, original, data)
+	})
+}
+
+func TestDeclaredPlatforms(t *testing.T) {
+	assert.Empty(t, declaredPlatforms(nil))
+	assert.Empty(t, declaredPlatforms(&mattermostModel.Manifest{}))
+	assert.Empty(t, declaredPlatforms(&mattermostModel.Manifest{Server: &mattermostModel.ManifestServer{Executable: "server/dist/plugin"}}))
+
+	manifest := &mattermostModel.Manifest{Server: &mattermostModel.ManifestServer{Executables: map[string]string{
+		model.WindowsAmd64: "server/dist/plugin-windows-amd64.exe",
+		model.LinuxArm64:   "server/dist/plugin-linux-arm64",
+		model.LinuxAmd64:   "server/dist/plugin-linux-amd64",
+	}}}
+	assert.Equal(t, []string{model.LinuxAmd64, model.LinuxArm64, model.WindowsAmd64}, declaredPlatforms(manifest))
+}
+
+func TestReconcileRemoteBundles(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-osx-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+	bundles.serveData(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-windows-amd64.tar.gz", []byte("bundle"))
+
+	t.Run("declared platforms", func(t *testing.T) {
+		bundles.requests = nil
+		hook := test.NewLocal(logger)
+		t.Cleanup(hook.Reset)
+
+		declared := []string{model.DarwinArm64, model.LinuxAmd64, model.LinuxArm64, model.WindowsAmd64}
+		platforms, err := reconcileRemoteBundles(fakePluginHost, "mattermost-plugin-demo-v0.2.0", declared)
+		require.NoError(t, err)
+
+		// The windows-amd64 bundle has no signature, and the arm64 platforms are not supported.
+		assert.Equal(t, []string{model.LinuxAmd64}, platforms)
+		assert.Len(t, bundles.requests, 6)
+		assert.Contains(t, bundles.requests, "HEAD "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-windows-amd64.tar.gz.sig")
+		assert.NotContains(t, bundles.requests, "HEAD "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-arm64.tar.gz")
+		assert.NotContains(t, bundles.requests, "HEAD "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-osx-arm64.tar.gz")
+
+		var warnings []string
+		for _, entry := range hook.AllEntries() {
+			warnings = append(warnings, entry.Message)
+		}
+		assert.Contains(t, warnings, "Manifest declares an executable for linux-arm64, but platform-specific bundles for it are not supported")
+		assert.Contains(t, warnings, "Manifest declares an executable for darwin-arm64, but platform-specific bundles for it are not supported")
+		assert.Contains(t, warnings, "Manifest declares an executable for windows-amd64, but no signed bundle is published at "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-windows-amd64.tar.gz")
+		assert.Contains(t, warnings, "A signed bundle is published at "+fakePluginHost+"/mattermost-plugin-demo-v0.2.0-osx-amd64.tar.gz, but the manifest declares no executable for darwin-amd64, ignoring it")
+	})
+
+	t.Run("undeclared platforms are ignored", func(t *testing.T) {
+		platforms, err := reconcileRemoteBundles(fakePluginHost, "mattermost-plugin-demo-v0.2.0", nil)
+		require.NoError(t, err)
+		assert.Empty(t, platforms)
+	})
+}
+
+func TestAddPlatformSpecificBundles(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundleURL := fakePluginHost + "/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz"
+	bundles.serve(bundleURL, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	plugin := makePlugin("com.mattermost.demo-plugin", "0.2.0")
+	plugin.RepoName = "mattermost-plugin-demo"
+	plugin.Manifest.Server = &mattermostModel.ManifestServer{
+		Executables: map[string]string{model.LinuxAmd64: "server/dist/plugin-linux-amd64"},
+	}
+
+	_, err := addPlatformSpecificBundles(plugin, fakePluginHost)
+	require.NoError(t, err)
+	assert.Contains(t, bundles.requests, "GET "+bundleURL)
+
+	checksum, size := testdataChecksum(t, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+	assert.Equal(t, checksum, plugin.Platforms.LinuxAmd64.SHA256)
+	assert.Equal(t, size, plugin.Platforms.LinuxAmd64.Size)
+
+	t.Run("unchanged bundles are not downloaded", func(t *testing.T) {
+		bundles.requests = nil
+		platforms := plugin.Platforms
+
+		_, err := addPlatformSpecificBundles(plugin, fakePluginHost)
+		require.NoError(t, err)
+		assert.NotContains(t, bundles.requests, "GET "+bundleURL)
+		assert.Equal(t, platforms, plugin.Platforms)
+	})
+
+	t.Run("changed bundles are downloaded", func(t *testing.T) {
+		bundles.requests = nil
+		signature := plugin.Platforms.LinuxAmd64.Signature
+		plugin.Platforms.LinuxAmd64.Signature = "c2lnbmF0dXJl"
+		plugin.Platforms.LinuxAmd64.SHA256 = ""
+
+		_, err := addPlatformSpecificBundles(plugin, fakePluginHost)
+		require.NoError(t, err)
+		assert.Contains(t, bundles.requests, "GET "+bundleURL)
+		assert.Equal(t, signature, plugin.Platforms.LinuxAmd64.Signature)
+		assert.Equal(t, checksum, plugin.Platforms.LinuxAmd64.SHA256)
+	})
+}
+
+func TestMigrateBackfillChecksums(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0.tar.gz", "mattermost-plugin-demo-v0.2.0.tar.gz")
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	dbFile := writeDatabase(t, `{
+		"schema_version": 2,
+		"plugins": [
+			{
+				"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+				"repo_name": "mattermost-plugin-demo",
+				"author_type": "mattermost",
+				"release_stage": "production",
+				"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0"},
+				"platforms": {"linux-amd64": {"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz"}},
+				"updated_at": "2026-10-01T12:00:00Z"
+			}
+		]
+	}`)
+
+	err := runGenerator(t, "migrate", "--database", dbFile)
+	require.NoError(t, err)
+	assert.Empty(t, bundles.requests)
+
+	err = runGenerator(t, "migrate", "--database", dbFile, "--backfill-checksums")
+	require.NoError(t, err)
+
+	plugins, err := pluginsFromDatabase(dbFile)
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+
+	checksum, size := testdataChecksum(t, "mattermost-plugin-demo-v0.2.0.tar.gz")
+	assert.Equal(t, checksum, plugins[0].SHA256)
+	assert.Equal(t, size, plugins[0].Size)
+
+	checksum, size = testdataChecksum(t, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+	assert.Equal(t, checksum, plugins[0].Platforms.LinuxAmd64.SHA256)
+	assert.Equal(t, size, plugins[0].Platforms.LinuxAmd64.Size)
+}
+
+func TestMigratedListDatabase(t *testing.T) {
+	_, bundles := setupFakes(t)
+
+	// A bare list written before schema versions, whose entries already have their metadata.
+	dbFile := writeDatabase(t, `[
+		{
+			"download_url": "https://plugins.example.com/release/mattermost-plugin-demo-v0.2.0.tar.gz",
+			"repo_name": "mattermost-plugin-demo",
+			"author_type": "mattermost",
+			"release_stage": "production",
+			"manifest": {"id": "com.mattermost.demo-plugin", "name": "Demo Plugin", "version": "0.2.0"},
+			"updated_at": "2026-10-01T12:00:00Z"
+		}
+	]`)
+
+	err := runGenerator(t, "migrate", "--database", dbFile, "--remote-plugin-store", fakePluginHost)
+	require.NoError(t, err)
+
+	err = runGenerator(t, "edit", "--database", dbFile, "com.mattermost.demo-plugin", "--set", "release_stage=beta")
+	require.NoError(t, err)
+	assert.Empty(t, bundles.requests)
+
+	database, err := databaseFromFile(dbFile)
+	require.NoError(t, err)
+	assert.Equal(t, model.SchemaVersion, database.SchemaVersion)
+	require.Len(t, database.Plugins, 1)
+	assert.Equal(t, model.Beta, database.Plugins[0].ReleaseStage)
+}
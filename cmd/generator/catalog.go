@@ -0,0 +1,80 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/store"
+)
+
+func init() {
+	generatorCmd.AddCommand(catalogCmd)
+
+	catalogCmd.Flags().String("catalog", "cmd/lambda/plugins.json.gz", "Path to which to write the compressed catalog.")
+}
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Build the compressed, pre-validated catalog embedded into the lambda function.",
+	Long: "The catalog command validates the plugins database and writes it as compact, gzipped JSON. " +
+		"Since the catalog is validated here, the lambda function can skip validation on startup.",
+	Example: "generator catalog --catalog cmd/lambda/plugins.json.gz",
+	RunE: func(command *cobra.Command, _ []string) error {
+		command.SilenceUsage = true
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		catalogPath, err := command.Flags().GetString("catalog")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		if _, err = store.NewStatic(plugins, logger); err != nil {
+			return errors.Wrap(err, "failed to validate plugins")
+		}
+
+		// The store relies on valid versions without checking them when serving requests.
+		for _, plugin := range plugins {
+			if _, err = semver.Parse(plugin.Manifest.Version); err != nil {
+				return errors.Wrapf(err, "failed to parse version for plugin %s", plugin.Manifest.Id)
+			}
+		}
+
+		// Write to a temporary file first, so that an interrupted build never leaves a truncated
+		// catalog to be embedded.
+		err = writeFileAtomically(catalogPath, func(file *os.File) error {
+			gzipWriter, err := gzip.NewWriterLevel(file, gzip.BestCompression)
+			if err != nil {
+				return errors.Wrap(err, "failed to create gzip writer")
+			}
+
+			encoder := json.NewEncoder(gzipWriter)
+			encoder.SetEscapeHTML(false)
+			if err = encoder.Encode(plugins); err != nil {
+				return err
+			}
+
+			return gzipWriter.Close()
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to write catalog %s", catalogPath)
+		}
+
+		logger.Infof("Wrote catalog of %d plugins to %s", len(plugins), catalogPath)
+
+		return nil
+	},
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "plugins.json")
+	catalogPath := filepath.Join(dir, "plugins.json.gz")
+
+	t.Run("writes the catalog", func(t *testing.T) {
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("jira", "3.0.0")}))
+
+		err := runGenerator(t, "catalog", "--database", dbFile, "--catalog", catalogPath)
+		require.NoError(t, err)
+
+		file, err := os.Open(catalogPath)
+		require.NoError(t, err)
+		defer file.Close()
+
+		gzipReader, err := gzip.NewReader(file)
+		require.NoError(t, err)
+
+		plugins, err := model.PluginsFromReader(gzipReader)
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, "jira", plugins[0].Manifest.Id)
+	})
+
+	t.Run("failure leaves the catalog untouched", func(t *testing.T) {
+		original, err := os.ReadFile(catalogPath)
+		require.NoError(t, err)
+
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("jira", "invalid")}))
+
+		err = runGenerator(t, "catalog", "--database", dbFile, "--catalog", catalogPath)
+		require.Error(t, err)
+
+		data, err := os.ReadFile(catalogPath)
+		require.NoError(t, err)
+		assert.Equal(t, original, data)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+}
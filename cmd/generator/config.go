@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// currentConfigVersion is the only version of the generator config understood by this generator.
+const currentConfigVersion = 1
+
+// generatorConfig describes the repositories synced by the generator root command.
+type generatorConfig struct {
+	Version int         `json:"version"`
+	Orgs    []orgConfig `json:"orgs"`
+}
+
+// orgConfig describes the repositories of a GitHub organization to sync.
+type orgConfig struct {
+	Name     string         `json:"name"`
+	Defaults pluginDefaults `json:"defaults"`
+	Repos    []repoConfig   `json:"repos"`
+}
+
+// repoConfig describes a repository to sync, overriding the defaults of its organization.
+type repoConfig struct {
+	Name        string         `json:"name"`
+	Defaults    pluginDefaults `json:"defaults"`
+	IncludeTags []string       `json:"include_tags,omitempty"` // Only sync releases with a tag matching one of these globs
+	ExcludeTags []string       `json:"exclude_tags,omitempty"` // Never sync releases with a tag matching one of these globs
+}
+
+// pluginDefaults are the metadata applied to newly discovered plugin releases. Unset fields fall
+// back to the defaults of the enclosing organization.
+type pluginDefaults struct {
+	AuthorType   model.AuthorType   `json:"author_type,omitempty"`
+	Hosting      model.HostingType  `json:"hosting,omitempty"`
+	Enterprise   *bool              `json:"enterprise,omitempty"`
+	ReleaseStage model.ReleaseStage `json:"release_stage,omitempty"`
+}
+
+// syncedRepository is a repository to sync, with the defaults of its organization applied.
+type syncedRepository struct {
+	Org         string
+	Name        string
+	Defaults    pluginDefaults
+	IncludeTags []string
+	ExcludeTags []string
+}
+
+// loadConfig reads and validates the generator config at the given path.
+func loadConfig(configPath string) (*generatorConfig, error) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open config %s", configPath)
+	}
+	defer file.Close()
+
+	config := &generatorConfig{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config %s", configPath)
+	}
+
+	if err = config.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid config %s", configPath)
+	}
+
+	return config, nil
+}
+
+func (config *generatorConfig) validate() error {
+	if config.Version != currentConfigVersion {
+		return errors.Errorf("unsupported version %d, expected %d", config.Version, currentConfigVersion)
+	}
+
+	orgs := make(map[string]bool)
+	for _, org := range config.Orgs {
+		if org.Name == "" {
+			return errors.New("org name must not be empty")
+		}
+		if orgs[org.Name] {
+			return errors.Errorf("org %s is listed more than once", org.Name)
+		}
+		orgs[org.Name] = true
+
+		if err := org.Defaults.validate(); err != nil {
+			return errors.Wrapf(err, "invalid defaults for org %s", org.Name)
+		}
+
+		repos := make(map[string]bool)
+		for _, repo := range org.Repos {
+			if repo.Name == "" {
+				return errors.Errorf("repo name must not be empty in org %s", org.Name)
+			}
+			if repos[repo.Name] {
+				return errors.Errorf("repo %s/%s is listed more than once", org.Name, repo.Name)
+			}
+			repos[repo.Name] = true
+
+			if err := repo.Defaults.validate(); err != nil {
+				return errors.Wrapf(err, "invalid defaults for repo %s/%s", org.Name, repo.Name)
+			}
+
+			for _, pattern := range slices.Concat(repo.IncludeTags, repo.ExcludeTags) {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return errors.Wrapf(err, "invalid tag pattern %q for repo %s/%s", pattern, org.Name, repo.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (defaults pluginDefaults) validate() error {
+	switch defaults.AuthorType {
+	case "", model.Mattermost, model.Partner, model.Community:
+	default:
+		return errors.Errorf("unknown author type %s", defaults.AuthorType)
+	}
+
+	switch defaults.Hosting {
+	case "", model.OnPrem, model.Cloud:
+	default:
+		return errors.Errorf("unknown hosting %s", defaults.Hosting)
+	}
+
+	switch defaults.ReleaseStage {
+	case "", model.Production, model.Beta, model.Experimental:
+	default:
+		return errors.Errorf("unknown release stage %s", defaults.ReleaseStage)
+	}
+
+	return nil
+}
+
+// overriddenBy returns the defaults with any fields set in the given overrides replaced.
+func (defaults pluginDefaults) overriddenBy(overrides pluginDefaults) pluginDefaults {
+	if overrides.AuthorType != "" {
+		defaults.AuthorType = overrides.AuthorType
+	}
+	if overrides.Hosting != "" {
+		defaults.Hosting = overrides.Hosting
+	}
+	if overrides.Enterprise != nil {
+		defaults.Enterprise = overrides.Enterprise
+	}
+	if overrides.ReleaseStage != "" {
+		defaults.ReleaseStage = overrides.ReleaseStage
+	}
+
+	return defaults
+}
+
+// applyTo sets the defaults on the given, newly discovered plugin.
+func (defaults pluginDefaults) applyTo(plugin *model.Plugin) {
+	plugin.AuthorType = defaults.AuthorType
+	plugin.Hosting = defaults.Hosting
+	plugin.Enterprise = defaults.Enterprise != nil && *defaults.Enterprise
+	plugin.ReleaseStage = defaults.ReleaseStage
+}
+
+// repositories returns the repositories to sync, optionally restricted to the given org.
+func (config *generatorConfig) repositories(orgFilter string) ([]syncedRepository, error) {
+	var repositories []syncedRepository
+	found := false
+	for _, org := range config.Orgs {
+		if orgFilter != "" && org.Name != orgFilter {
+			continue
+		}
+		found = true
+
+		for _, repo := range org.Repos {
+			repositories = append(repositories, syncedRepository{
+				Org:         org.Name,
+				Name:        repo.Name,
+				Defaults:    org.Defaults.overriddenBy(repo.Defaults),
+				IncludeTags: repo.IncludeTags,
+				ExcludeTags: repo.ExcludeTags,
+			})
+		}
+	}
+
+	if orgFilter != "" && !found {
+		return nil, errors.Errorf("org %s is not configured", orgFilter)
+	}
+
+	return repositories, nil
+}
+
+// syncsTag reports whether releases with the given tag are synced from the repository.
+func (repository syncedRepository) syncsTag(tag string) bool {
+	for _, pattern := range repository.ExcludeTags {
+		if matched, _ := path.Match(pattern, tag); matched {
+			return false
+		}
+	}
+
+	if len(repository.IncludeTags) == 0 {
+		return true
+	}
+
+	for _, pattern := range repository.IncludeTags {
+		if matched, _ := path.Match(pattern, tag); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), "generator.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+
+	return configFile
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("repository config", func(t *testing.T) {
+		config, err := loadConfig("../../generator.json")
+		require.NoError(t, err)
+
+		repositories, err := config.repositories("")
+		require.NoError(t, err)
+		assert.NotEmpty(t, repositories)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"))
+		require.Error(t, err)
+	})
+
+	for name, config := range map[string]string{
+		"unknown field":         `{"version":1,"orgs":[{"name":"mattermost","repositories":[]}]}`,
+		"unsupported version":   `{"version":2,"orgs":[]}`,
+		"missing version":       `{"orgs":[]}`,
+		"empty org name":        `{"version":1,"orgs":[{"name":""}]}`,
+		"duplicate org":         `{"version":1,"orgs":[{"name":"mattermost"},{"name":"mattermost"}]}`,
+		"empty repo name":       `{"version":1,"orgs":[{"name":"mattermost","repos":[{"name":""}]}]}`,
+		"duplicate repo":        `{"version":1,"orgs":[{"name":"mattermost","repos":[{"name":"jira"},{"name":"jira"}]}]}`,
+		"unknown author type":   `{"version":1,"orgs":[{"name":"mattermost","defaults":{"author_type":"vendor"}}]}`,
+		"unknown hosting":       `{"version":1,"orgs":[{"name":"mattermost","repos":[{"name":"jira","defaults":{"hosting":"hybrid"}}]}]}`,
+		"unknown release stage": `{"version":1,"orgs":[{"name":"mattermost","defaults":{"release_stage":"alpha"}}]}`,
+		"invalid tag pattern":   `{"version":1,"orgs":[{"name":"mattermost","repos":[{"name":"jira","exclude_tags":["v[1"]}]}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, config))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestConfigRepositories(t *testing.T) {
+	config, err := loadConfig(writeConfig(t, `{
+		"version": 1,
+		"orgs": [
+			{
+				"name": "mattermost",
+				"defaults": {"author_type": "mattermost", "release_stage": "production"},
+				"repos": [
+					{"name": "mattermost-plugin-jira"},
+					{"name": "mattermost-plugin-antivirus", "defaults": {"hosting": "on-prem", "enterprise": true, "release_stage": "beta"}}
+				]
+			},
+			{
+				"name": "matterpoll",
+				"defaults": {"author_type": "community", "enterprise": true},
+				"repos": [
+					{"name": "matterpoll", "defaults": {"enterprise": false}, "include_tags": ["v1.*"], "exclude_tags": ["v1.0.*"]}
+				]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+
+	enabled, disabled := true, false
+
+	t.Run("all orgs", func(t *testing.T) {
+		repositories, err := config.repositories("")
+		require.NoError(t, err)
+		assert.Equal(t, []syncedRepository{
+			{
+				Org:      "mattermost",
+				Name:     "mattermost-plugin-jira",
+				Defaults: pluginDefaults{AuthorType: model.Mattermost, ReleaseStage: model.Production},
+			},
+			{
+				Org:      "mattermost",
+				Name:     "mattermost-plugin-antivirus",
+				Defaults: pluginDefaults{AuthorType: model.Mattermost, Hosting: model.OnPrem, Enterprise: &enabled, ReleaseStage: model.Beta},
+			},
+			{
+				Org:         "matterpoll",
+				Name:        "matterpoll",
+				Defaults:    pluginDefaults{AuthorType: model.Community, Enterprise: &disabled},
+				IncludeTags: []string{"v1.*"},
+				ExcludeTags: []string{"v1.0.*"},
+			},
+		}, repositories)
+	})
+
+	t.Run("filtered by org", func(t *testing.T) {
+		repositories, err := config.repositories("matterpoll")
+		require.NoError(t, err)
+		require.Len(t, repositories, 1)
+		assert.Equal(t, "matterpoll", repositories[0].Name)
+	})
+
+	t.Run("unknown org", func(t *testing.T) {
+		_, err := config.repositories("unknown")
+		require.Error(t, err)
+	})
+
+	t.Run("defaults applied to plugin", func(t *testing.T) {
+		repositories, err := config.repositories("mattermost")
+		require.NoError(t, err)
+
+		plugin := &model.Plugin{}
+		repositories[1].Defaults.applyTo(plugin)
+		assert.Equal(t, model.Mattermost, plugin.AuthorType)
+		assert.Equal(t, model.OnPrem, plugin.Hosting)
+		assert.True(t, plugin.Enterprise)
+		assert.Equal(t, model.Beta, plugin.ReleaseStage)
+
+		plugin = &model.Plugin{}
+		repositories[0].Defaults.applyTo(plugin)
+		assert.Equal(t, model.HostingType(""), plugin.Hosting)
+		assert.False(t, plugin.Enterprise)
+	})
+}
+
+func TestSyncsTag(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		includeTags []string
+		excludeTags []string
+		tag         string
+		expected    bool
+	}{
+		{"no rules", nil, nil, "v1.0.0", true},
+		{"included", []string{"v1.*", "v2.*"}, nil, "v2.1.0", true},
+		{"not included", []string{"v1.*"}, nil, "v2.1.0", false},
+		{"excluded", nil, []string{"*-rc*"}, "v2.1.0-rc1", false},
+		{"not excluded", nil, []string{"*-rc*"}, "v2.1.0", true},
+		{"exclusion wins over inclusion", []string{"v2.*"}, []string{"v2.0.*"}, "v2.0.1", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repository := syncedRepository{IncludeTags: tc.includeTags, ExcludeTags: tc.excludeTags}
+			assert.Equal(t, tc.expected, repository.syncsTag(tc.tag))
+		})
+	}
+}
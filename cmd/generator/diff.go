@@ -0,0 +1,386 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/output"
+)
+
+func init() {
+	generatorCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().String("format", output.Table, "The output format, either table, json or markdown.")
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old database> <new database>",
+	Short: "Summarize the changes between two plugins.json databases.",
+	Long: "The diff command reports, per plugin, the versions added to and removed from the new database, and the " +
+		"fields that changed for versions in both, e.g. URLs, signatures, metadata and the minimum server version. " +
+		"Icon changes are summarized by size and checksum rather than printed in full.\n\n" +
+		"Use --format markdown to paste the summary into a pull request description.",
+	Example: "generator diff plugins.json.orig plugins.json --format markdown",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		format, _ := command.Flags().GetString("format")
+		if format != output.Table && format != output.JSON && format != output.Markdown {
+			return errors.Errorf("unsupported format %s, expected %s, %s or %s", format, output.Table, output.JSON, output.Markdown)
+		}
+
+		// Compare databases at any schema version, e.g. before and after a migration.
+		oldDatabase, err := databaseFromFile(args[0])
+		if err != nil {
+			return errors.Wrap(err, "failed to read old plugins database")
+		}
+
+		newDatabase, err := databaseFromFile(args[1])
+		if err != nil {
+			return errors.Wrap(err, "failed to read new plugins database")
+		}
+
+		diffs := diffDatabases(oldDatabase.Plugins, newDatabase.Plugins)
+
+		switch format {
+		case output.JSON:
+			encoder := json.NewEncoder(command.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(diffs)
+		case output.Markdown:
+			err = writeDiffMarkdown(command.OutOrStdout(), diffs)
+		default:
+			err = writeDiffTable(command.OutOrStdout(), diffs)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to write diff")
+		}
+
+		return nil
+	},
+}
+
+// pluginDiff describes how the releases of a plugin differ between two databases.
+type pluginDiff struct {
+	ID      string        `json:"id"`
+	Added   []string      `json:"added,omitempty"`
+	Removed []string      `json:"removed,omitempty"`
+	Changed []versionDiff `json:"changed,omitempty"`
+}
+
+// versionDiff lists the fields that changed for a release present in both databases.
+type versionDiff struct {
+	Version string        `json:"version"`
+	Changes []fieldChange `json:"changes"`
+}
+
+// fieldChange is a change of a single field of a release. Icons, signatures and checksums are
+// summarized rather than given in full.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// diffDatabases compares the releases of the given databases, returning the plugins with added,
+// removed or changed releases sorted by id, each with its versions sorted in descending order.
+func diffDatabases(oldPlugins, newPlugins []*model.Plugin) []pluginDiff {
+	oldReleases := releasesByID(oldPlugins)
+	newReleases := releasesByID(newPlugins)
+
+	var ids []string
+	for id := range oldReleases {
+		ids = append(ids, id)
+	}
+	for id := range newReleases {
+		if _, ok := oldReleases[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	diffs := []pluginDiff{}
+	for _, id := range ids {
+		diff := pluginDiff{ID: id}
+
+		for _, version := range sortedVersions(newReleases[id]) {
+			oldPlugin, ok := oldReleases[id][version]
+			if !ok {
+				diff.Added = append(diff.Added, version)
+				continue
+			}
+
+			if changes := diffReleases(oldPlugin, newReleases[id][version]); len(changes) > 0 {
+				diff.Changed = append(diff.Changed, versionDiff{Version: version, Changes: changes})
+			}
+		}
+
+		for _, version := range sortedVersions(oldReleases[id]) {
+			if _, ok := newReleases[id][version]; !ok {
+				diff.Removed = append(diff.Removed, version)
+			}
+		}
+
+		if len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs
+}
+
+// releasesByID maps each plugin id to its releases by version. Entries without a manifest are
+// ignored.
+func releasesByID(plugins []*model.Plugin) map[string]map[string]*model.Plugin {
+	releases := make(map[string]map[string]*model.Plugin)
+	for _, plugin := range plugins {
+		if plugin.Manifest == nil {
+			continue
+		}
+
+		if releases[plugin.Manifest.Id] == nil {
+			releases[plugin.Manifest.Id] = make(map[string]*model.Plugin)
+		}
+		releases[plugin.Manifest.Id][plugin.Manifest.Version] = plugin
+	}
+
+	return releases
+}
+
+// sortedVersions returns the versions of the given releases in descending order, falling back to
+// comparing invalid versions as strings.
+func sortedVersions(releases map[string]*model.Plugin) []string {
+	versions := make([]string, 0, len(releases))
+	for version := range releases {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		vi, errI := semver.Parse(versions[i])
+		vj, errJ := semver.Parse(versions[j])
+		if errI != nil || errJ != nil {
+			return versions[i] > versions[j]
+		}
+
+		return vi.GT(vj)
+	})
+
+	return versions
+}
+
+// releaseField is a field of a release compared by the diff, rendered as a string. Large values
+// are compared in full, but reported in summarized form.
+type releaseField struct {
+	name      string
+	value     func(plugin *model.Plugin) string
+	summarize func(value string) string
+}
+
+// releaseFields are the fields compared by the diff, in the order they are reported.
+var releaseFields = append([]releaseField{
+	{"name", func(p *model.Plugin) string { return p.Manifest.Name }, nil},
+	{"description", func(p *model.Plugin) string { return p.Manifest.Description }, nil},
+	{"min_server_version", func(p *model.Plugin) string { return p.Manifest.MinServerVersion }, nil},
+	{"manifest", otherManifestFields, summarizeData},
+	{"author_type", func(p *model.Plugin) string { return string(p.AuthorType) }, nil},
+	{"hosting", func(p *model.Plugin) string { return string(p.Hosting) }, nil},
+	{"enterprise", func(p *model.Plugin) string { return fmt.Sprint(p.Enterprise) }, nil},
+	{"release_stage", func(p *model.Plugin) string { return string(p.ReleaseStage) }, nil},
+	{"labels", func(p *model.Plugin) string {
+		names := make([]string, 0, len(p.Labels))
+		for _, label := range p.Labels {
+			names = append(names, label.Name)
+		}
+		return strings.Join(names, ", ")
+	}, nil},
+	{"repo_name", func(p *model.Plugin) string { return p.RepoName }, nil},
+	{"homepage_url", func(p *model.Plugin) string { return p.HomepageURL }, nil},
+	{"release_notes_url", func(p *model.Plugin) string { return p.ReleaseNotesURL }, nil},
+	{"download_url", func(p *model.Plugin) string { return p.DownloadURL }, nil},
+	{"signature", func(p *model.Plugin) string { return p.Signature }, abbreviate},
+	{"sha256", func(p *model.Plugin) string { return p.SHA256 }, abbreviate},
+	{"size", func(p *model.Plugin) string { return formatSize(p.Size) }, nil},
+	{"icon_data", func(p *model.Plugin) string { return p.IconData }, summarizeData},
+	{"updated_at", func(p *model.Plugin) string { return p.UpdatedAt.UTC().Format(time.RFC3339) }, nil},
+	{"promoted_at", func(p *model.Plugin) string {
+		if p.PromotedAt == nil {
+			return ""
+		}
+		return p.PromotedAt.UTC().Format(time.RFC3339)
+	}, nil},
+}, platformBundleFields()...)
+
+// platformBundleFields returns the fields of each platform-specific bundle compared by the diff.
+func platformBundleFields() []releaseField {
+	platforms := []struct {
+		name   string
+		bundle func(p *model.Plugin) model.PlatformBundleMetadata
+	}{
+		{model.LinuxAmd64, func(p *model.Plugin) model.PlatformBundleMetadata { return p.Platforms.LinuxAmd64 }},
+		{model.DarwinAmd64, func(p *model.Plugin) model.PlatformBundleMetadata { return p.Platforms.DarwinAmd64 }},
+		{model.WindowsAmd64, func(p *model.Plugin) model.PlatformBundleMetadata { return p.Platforms.WindowsAmd64 }},
+	}
+
+	var fields []releaseField
+	for _, platform := range platforms {
+		prefix := "platforms." + platform.name + "."
+		bundle := platform.bundle
+		fields = append(fields,
+			releaseField{prefix + "download_url", func(p *model.Plugin) string { return bundle(p).DownloadURL }, nil},
+			releaseField{prefix + "signature", func(p *model.Plugin) string { return bundle(p).Signature }, abbreviate},
+			releaseField{prefix + "sha256", func(p *model.Plugin) string { return bundle(p).SHA256 }, abbreviate},
+			releaseField{prefix + "size", func(p *model.Plugin) string { return formatSize(bundle(p).Size) }, nil},
+		)
+	}
+
+	return fields
+}
+
+// diffReleases lists the fields that differ between two entries of the same release.
+func diffReleases(oldPlugin, newPlugin *model.Plugin) []fieldChange {
+	var changes []fieldChange
+	for _, field := range releaseFields {
+		oldValue, newValue := field.value(oldPlugin), field.value(newPlugin)
+		if oldValue == newValue {
+			continue
+		}
+
+		if field.summarize != nil {
+			oldValue, newValue = field.summarize(oldValue), field.summarize(newValue)
+		}
+		changes = append(changes, fieldChange{Field: field.name, Old: oldValue, New: newValue})
+	}
+
+	return changes
+}
+
+// otherManifestFields encodes the parts of the manifest not compared as separate fields, e.g. its
+// settings schema, so that changes to them are reported.
+func otherManifestFields(plugin *model.Plugin) string {
+	manifest := *plugin.Manifest
+	manifest.Name = ""
+	manifest.Description = ""
+	manifest.MinServerVersion = ""
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Sprintf("invalid manifest: %s", err)
+	}
+
+	return string(data)
+}
+
+// summarizeData summarizes large values, such as base64 encoded icons, by their size and the start
+// of their SHA-256 checksum.
+func summarizeData(data string) string {
+	if data == "" {
+		return ""
+	}
+
+	checksum := sha256.Sum256([]byte(data))
+
+	return fmt.Sprintf("%s, sha256 %s", formatSize(int64(len(data))), hex.EncodeToString(checksum[:])[:12])
+}
+
+// abbreviate shortens long values such as signatures to their first and last characters.
+func abbreviate(value string) string {
+	if len(value) <= 24 {
+		return value
+	}
+
+	return value[:10] + "..." + value[len(value)-10:]
+}
+
+// formatSize formats the given number of bytes, or returns an empty string if unknown.
+func formatSize(size int64) string {
+	if size == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// writeDiffTable writes a row for each added, removed or changed field of a release.
+func writeDiffTable(w io.Writer, diffs []pluginDiff) error {
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tVERSION\tCHANGE\tFIELD\tOLD\tNEW")
+	for _, diff := range diffs {
+		for _, version := range diff.Added {
+			fmt.Fprintf(tw, "%s\t%s\tadded\t-\t-\t-\n", diff.ID, version)
+		}
+		for _, version := range diff.Removed {
+			fmt.Fprintf(tw, "%s\t%s\tremoved\t-\t-\t-\n", diff.ID, version)
+		}
+		for _, changed := range diff.Changed {
+			for _, change := range changed.Changes {
+				fmt.Fprintf(tw, "%s\t%s\tchanged\t%s\t%s\t%s\n", diff.ID, changed.Version, change.Field, output.OrDash(change.Old), output.OrDash(change.New))
+			}
+		}
+	}
+
+	return tw.Flush()
+}
+
+// writeDiffMarkdown writes a section for each plugin, listing its added and removed versions and a
+// table of the changed fields of each changed version.
+func writeDiffMarkdown(w io.Writer, diffs []pluginDiff) error {
+	var added, removed, changed int
+	for _, diff := range diffs {
+		added += len(diff.Added)
+		removed += len(diff.Removed)
+		changed += len(diff.Changed)
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d plugins differ: %d releases added, %d removed and %d changed.\n", len(diffs), added, removed, changed)
+
+	for _, diff := range diffs {
+		fmt.Fprintf(b, "\n### %s\n\n", diff.ID)
+		if len(diff.Added) > 0 {
+			fmt.Fprintf(b, "- Added: %s\n", strings.Join(diff.Added, ", "))
+		}
+		if len(diff.Removed) > 0 {
+			fmt.Fprintf(b, "- Removed: %s\n", strings.Join(diff.Removed, ", "))
+		}
+
+		for _, changed := range diff.Changed {
+			fmt.Fprintf(b, "- Changed: %s\n\n", changed.Version)
+			fmt.Fprintln(b, "  | Field | Old | New |")
+			fmt.Fprintln(b, "  | --- | --- | --- |")
+			for _, change := range changed.Changes {
+				fmt.Fprintf(b, "  | %s | %s | %s |\n", change.Field, markdownCode(change.Old), markdownCode(change.New))
+			}
+			fmt.Fprintln(b)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// markdownCode formats the given value as inline code for a Markdown table cell.
+func markdownCode(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return "`" + strings.ReplaceAll(value, "|", "\\|") + "`"
+}
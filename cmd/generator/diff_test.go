@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestDiffDatabases(t *testing.T) {
+	t.Run("identical databases", func(t *testing.T) {
+		plugins := []*model.Plugin{makeValidPlugin("com.example.a", "1.0.0")}
+		assert.Empty(t, diffDatabases(plugins, []*model.Plugin{makeValidPlugin("com.example.a", "1.0.0")}))
+	})
+
+	t.Run("added, removed and changed versions", func(t *testing.T) {
+		oldPlugins := []*model.Plugin{
+			makeValidPlugin("com.example.a", "1.1.0"),
+			makeValidPlugin("com.example.a", "1.0.0"),
+			makeValidPlugin("com.example.b", "1.0.0"),
+			makeValidPlugin("com.example.c", "1.0.0"),
+		}
+
+		changed := makeValidPlugin("com.example.a", "1.1.0")
+		changed.Manifest.MinServerVersion = "9.0.0"
+		changed.Enterprise = true
+		changed.DownloadURL = "https://example.com/a-1.1.0-fixed.tar.gz"
+		changed.IconData = svgDataURIPrefix + strings.Repeat("A", 100)
+		changed.Platforms.LinuxAmd64 = model.PlatformBundleMetadata{
+			DownloadURL: "https://example.com/a-1.1.0-linux-amd64.tar.gz",
+			Signature:   strings.Repeat("S", 100),
+		}
+
+		newPlugins := []*model.Plugin{
+			makeValidPlugin("com.example.a", "1.2.0"),
+			changed,
+			makeValidPlugin("com.example.a", "1.0.0"),
+			makeValidPlugin("com.example.c", "1.0.0"),
+			makeValidPlugin("com.example.d", "0.1.0"),
+		}
+
+		diffs := diffDatabases(oldPlugins, newPlugins)
+		require.Len(t, diffs, 3)
+
+		assert.Equal(t, "com.example.a", diffs[0].ID)
+		assert.Equal(t, []string{"1.2.0"}, diffs[0].Added)
+		assert.Empty(t, diffs[0].Removed)
+		require.Len(t, diffs[0].Changed, 1)
+		assert.Equal(t, "1.1.0", diffs[0].Changed[0].Version)
+
+		changes := diffs[0].Changed[0].Changes
+		require.Len(t, changes, 6)
+		assert.Equal(t, fieldChange{Field: "min_server_version", Old: "", New: "9.0.0"}, changes[0])
+		assert.Equal(t, fieldChange{Field: "enterprise", Old: "false", New: "true"}, changes[1])
+		assert.Equal(t, fieldChange{Field: "download_url", Old: "https://plugins.releases.mattermost.com/release/com.example.a-v1.1.0.tar.gz", New: "https://example.com/a-1.1.0-fixed.tar.gz"}, changes[2])
+		assert.Equal(t, "icon_data", changes[3].Field)
+		assert.Regexp(t, `^\d+ bytes, sha256 [0-9a-f]{12}$`, changes[3].Old)
+		assert.Regexp(t, `^126 bytes, sha256 [0-9a-f]{12}$`, changes[3].New)
+		assert.Equal(t, fieldChange{Field: "platforms.linux-amd64.download_url", Old: "", New: "https://example.com/a-1.1.0-linux-amd64.tar.gz"}, changes[4])
+		assert.Equal(t, fieldChange{Field: "platforms.linux-amd64.signature", Old: "", New: "SSSSSSSSSS...SSSSSSSSSS"}, changes[5])
+
+		assert.Equal(t, pluginDiff{ID: "com.example.b", Removed: []string{"1.0.0"}}, diffs[1])
+		assert.Equal(t, pluginDiff{ID: "com.example.d", Added: []string{"0.1.0"}}, diffs[2])
+	})
+
+	t.Run("signatures differing only in the middle", func(t *testing.T) {
+		oldPlugin := makeValidPlugin("com.example.a", "1.0.0")
+		oldPlugin.Signature = strings.Repeat("A", 50) + "B" + strings.Repeat("A", 50)
+		newPlugin := makeValidPlugin("com.example.a", "1.0.0")
+		newPlugin.Signature = strings.Repeat("A", 101)
+
+		diffs := diffDatabases([]*model.Plugin{oldPlugin}, []*model.Plugin{newPlugin})
+		require.Len(t, diffs, 1)
+		require.Len(t, diffs[0].Changed, 1)
+		assert.Equal(t, "signature", diffs[0].Changed[0].Changes[0].Field)
+	})
+
+	t.Run("manifest settings", func(t *testing.T) {
+		newPlugin := makeValidPlugin("com.example.a", "1.0.0")
+		newPlugin.Manifest.HomepageURL = "https://example.com/a"
+
+		diffs := diffDatabases([]*model.Plugin{makeValidPlugin("com.example.a", "1.0.0")}, []*model.Plugin{newPlugin})
+		require.Len(t, diffs, 1)
+		require.Len(t, diffs[0].Changed, 1)
+		assert.Equal(t, "manifest", diffs[0].Changed[0].Changes[0].Field)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.json")
+	newFile := filepath.Join(dir, "new.json")
+
+	changed := makeValidPlugin("com.example.a", "1.0.0")
+	changed.ReleaseStage = model.Beta
+
+	require.NoError(t, pluginsToDatabase(oldFile, []*model.Plugin{makeValidPlugin("com.example.a", "1.0.0")}))
+	require.NoError(t, pluginsToDatabase(newFile, []*model.Plugin{makeValidPlugin("com.example.a", "1.1.0"), changed}))
+
+	runDiff := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+
+		output := &bytes.Buffer{}
+		generatorCmd.SetOut(output)
+		t.Cleanup(func() {
+			generatorCmd.SetOut(nil)
+		})
+
+		err := runGenerator(t, append([]string{"diff", oldFile, newFile}, args...)...)
+
+		return output.String(), err
+	}
+
+	t.Run("table", func(t *testing.T) {
+		output, err := runDiff(t)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"ID", "VERSION", "CHANGE", "FIELD", "OLD", "NEW"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"com.example.a", "1.1.0", "added", "-", "-", "-"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"com.example.a", "1.0.0", "changed", "release_stage", "production", "beta"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		output, err := runDiff(t, "--format", "markdown")
+		require.NoError(t, err)
+
+		assert.Equal(t, "1 plugins differ: 1 releases added, 0 removed and 1 changed.\n"+
+			"\n"+
+			"### com.example.a\n"+
+			"\n"+
+			"- Added: 1.1.0\n"+
+			"- Changed: 1.0.0\n"+
+			"\n"+
+			"  | Field | Old | New |\n"+
+			"  | --- | --- | --- |\n"+
+			"  | release_stage | `production` | `beta` |\n"+
+			"\n", output)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := runDiff(t, "--format", "yaml")
+		require.EqualError(t, err, "unsupported format yaml, expected table, json or markdown")
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		err := runGenerator(t, "diff", oldFile, filepath.Join(dir, "missing.json"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func init() {
+	generatorCmd.AddCommand(editCmd)
+
+	addMetadataFlags(editCmd.Flags())
+	editCmd.Flags().StringArray("set", nil, "Set a field of the releases, one of author_type, hosting, enterprise or release_stage, e.g. release_stage=beta. May be repeated.")
+	editCmd.Flags().Bool("reclassify", false, "Allow changing the author type or hosting of the releases")
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit <id> [version]",
+	Short: "Edit the metadata of plugin releases in the plugins.json database",
+	Long: "The edit command changes the author type, hosting, enterprise flag or release stage of the given release of a plugin, " +
+		"or of every release of the plugin if no version is given.\n\n" +
+		"Fields are set either with the same flags as the add command, e.g. --beta or --cloud, or with --set field=value. " +
+		"Setting hosting to an empty value makes the plugin available to cloud and on-prem installations. " +
+		"Changing the author type or hosting requires --reclassify.",
+	Example: `  generator edit com.mattermost.plugin-todo 0.3.0 --set release_stage=beta
+  generator edit com.mattermost.plugin-todo --enterprise`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		id := args[0]
+		var version string
+		if len(args) > 1 {
+			version = strings.TrimPrefix(args[1], "v")
+		}
+
+		metadata, err := parseMetadataFlags(command)
+		if err != nil {
+			return err
+		}
+
+		assignments, err := command.Flags().GetStringArray("set")
+		if err != nil {
+			return err
+		}
+
+		err = metadata.set(assignments)
+		if err != nil {
+			return err
+		}
+
+		if metadata.authorType == nil && metadata.hosting == nil && metadata.enterprise == nil && metadata.releaseStage == nil {
+			return errors.New("nothing to edit, use --set or the metadata flags")
+		}
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		now := time.Now().In(time.UTC)
+		edited := 0
+		for _, plugin := range plugins {
+			if !matchesRelease(plugin, id, version) {
+				continue
+			}
+
+			original := *plugin
+			err = metadata.applyTo(plugin, &original)
+			if err != nil {
+				return errors.Wrapf(err, "failed to edit release %s of plugin %s", plugin.Manifest.Version, id)
+			}
+
+			if plugin.ReleaseStage != original.ReleaseStage {
+				plugin.PromotedAt = &now
+			}
+			edited++
+		}
+
+		if edited == 0 {
+			if version == "" {
+				return errors.Errorf("plugin %s has no releases", id)
+			}
+
+			return errors.Errorf("plugin %s has no release %s", id, version)
+		}
+
+		err = saveDatabase(command, dbFile, plugins)
+		if err != nil {
+			return errors.Wrap(err, "failed to write plugins database")
+		}
+
+		return nil
+	},
+}
+
+// set applies the given field=value assignments to the metadata, rejecting unknown fields and
+// values as well as fields already set by a flag or another assignment.
+func (metadata *metadataFlags) set(assignments []string) error {
+	for _, assignment := range assignments {
+		field, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return errors.Errorf("invalid assignment %q, expected field=value", assignment)
+		}
+
+		var alreadySet bool
+		switch field {
+		case "author_type":
+			authorType := model.AuthorType(value)
+			if !slices.Contains([]model.AuthorType{model.Mattermost, model.Partner, model.Community}, authorType) {
+				return errors.Errorf("unknown author type %q", value)
+			}
+			alreadySet = metadata.authorType != nil
+			metadata.authorType = &authorType
+		case "hosting":
+			hosting := model.HostingType(value)
+			if !slices.Contains([]model.HostingType{"", model.Cloud, model.OnPrem}, hosting) {
+				return errors.Errorf("unknown hosting %q", value)
+			}
+			alreadySet = metadata.hosting != nil
+			metadata.hosting = &hosting
+		case "enterprise":
+			enterprise, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.Errorf("invalid enterprise value %q, expected true or false", value)
+			}
+			alreadySet = metadata.enterprise != nil
+			metadata.enterprise = &enterprise
+		case "release_stage":
+			releaseStage := model.ReleaseStage(value)
+			if !slices.Contains([]model.ReleaseStage{model.Production, model.Beta, model.Experimental}, releaseStage) {
+				return errors.Errorf("unknown release stage %q", value)
+			}
+			alreadySet = metadata.releaseStage != nil
+			metadata.releaseStage = &releaseStage
+		default:
+			return errors.Errorf("unsupported field %q, expected one of author_type, hosting, enterprise or release_stage", field)
+		}
+
+		if alreadySet {
+			return errors.Errorf("%s is set more than once", field)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestEdit(t *testing.T) {
+	// setupDatabase writes a database holding two releases of a community plugin and a release of
+	// another plugin.
+	setupDatabase := func(t *testing.T) string {
+		t.Helper()
+
+		var plugins []*model.Plugin
+		for _, release := range [][2]string{{"com.example.a", "1.1.0"}, {"com.example.a", "1.0.0"}, {"com.example.b", "1.0.0"}} {
+			plugin := makePlugin(release[0], release[1])
+			plugin.AuthorType = model.Community
+			plugin.ReleaseStage = model.Production
+			plugins = append(plugins, plugin)
+		}
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, plugins))
+
+		return dbFile
+	}
+
+	readDatabase := func(t *testing.T, dbFile string) []*model.Plugin {
+		t.Helper()
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 3)
+
+		return plugins
+	}
+
+	t.Run("set a version", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "edit", "com.example.a", "1.1.0", "--set", "release_stage=beta", "--set", "enterprise=true", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins := readDatabase(t, dbFile)
+		assert.Equal(t, model.Beta, plugins[0].ReleaseStage)
+		assert.True(t, plugins[0].Enterprise)
+		assert.NotNil(t, plugins[0].PromotedAt)
+		assert.Equal(t, model.Production, plugins[1].ReleaseStage)
+		assert.False(t, plugins[1].Enterprise)
+		assert.Equal(t, model.Production, plugins[2].ReleaseStage)
+	})
+
+	t.Run("flags for all versions", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "edit", "com.example.a", "--experimental", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins := readDatabase(t, dbFile)
+		assert.Equal(t, model.Experimental, plugins[0].ReleaseStage)
+		assert.Equal(t, model.Experimental, plugins[1].ReleaseStage)
+		assert.Equal(t, model.Production, plugins[2].ReleaseStage)
+		assert.Equal(t, model.Community, plugins[0].AuthorType)
+		assert.Nil(t, plugins[2].PromotedAt)
+	})
+
+	t.Run("reclassify", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "edit", "com.example.a", "--set", "author_type=partner", "--database", dbFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "use --reclassify to confirm")
+		assert.Equal(t, model.Community, readDatabase(t, dbFile)[0].AuthorType)
+
+		err = runGenerator(t, "edit", "com.example.a", "--partner", "--cloud", "--reclassify", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins := readDatabase(t, dbFile)
+		assert.Equal(t, model.Partner, plugins[0].AuthorType)
+		assert.Equal(t, model.Cloud, plugins[0].Hosting)
+		assert.Equal(t, model.Partner, plugins[1].AuthorType)
+
+		err = runGenerator(t, "edit", "com.example.a", "--set", "hosting=", "--reclassify", "--database", dbFile)
+		require.NoError(t, err)
+		assert.Empty(t, readDatabase(t, dbFile)[0].Hosting)
+	})
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{"nothing to edit", []string{"com.example.a"}, "nothing to edit, use --set or the metadata flags"},
+		{"conflicting flags", []string{"com.example.a", "--beta", "--experimental"}, "can't set the release as more than one of production, beta and experimental"},
+		{"conflicting flag and assignment", []string{"com.example.a", "--beta", "--set", "release_stage=production"}, "release_stage is set more than once"},
+		{"invalid assignment", []string{"com.example.a", "--set", "beta"}, `invalid assignment "beta", expected field=value`},
+		{"unsupported field", []string{"com.example.a", "--set", "download_url=https://example.com"}, `unsupported field "download_url", expected one of author_type, hosting, enterprise or release_stage`},
+		{"unknown value", []string{"com.example.a", "--set", "release_stage=alpha"}, `unknown release stage "alpha"`},
+		{"invalid enterprise", []string{"com.example.a", "--set", "enterprise=maybe"}, `invalid enterprise value "maybe", expected true or false`},
+		{"unknown release", []string{"com.example.a", "2.0.0", "--beta"}, "plugin com.example.a has no release 2.0.0"},
+		{"unknown plugin", []string{"com.example.c", "--beta"}, "plugin com.example.c has no releases"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			dbFile := setupDatabase(t)
+
+			err := runGenerator(t, append(append([]string{"edit"}, testCase.args...), "--database", dbFile)...)
+			require.EqualError(t, err, testCase.expected)
+		})
+	}
+}
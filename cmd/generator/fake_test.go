@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePluginHost is the remote plugin store served by the fake bundle fetcher.
+const fakePluginHost = "https://plugins.example.com/release"
+
+// testdataChecksum returns the hex-encoded SHA-256 checksum and the size of the given testdata file.
+func testdataChecksum(t *testing.T, name string) (string, int64) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	checksum := sha256.Sum256(data)
+
+	return hex.EncodeToString(checksum[:]), int64(len(data))
+}
+
+// fakeBundleFetcher serves testdata files at configured URLs, responding with 404 Not Found to
+// any other URL.
+type fakeBundleFetcher struct {
+	lock     sync.Mutex
+	files    map[string]string
+	data     map[string][]byte
+	requests []string
+}
+
+// serve makes the given testdata file, and its signature, available at the given URL.
+func (f *fakeBundleFetcher) serve(url, name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.files[url] = name
+	f.files[url+".sig"] = name + ".sig"
+}
+
+// serveData makes the given data available at the given URL, taking precedence over testdata
+// files.
+func (f *fakeBundleFetcher) serveData(url string, data []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.data[url] = data
+}
+
+func (f *fakeBundleFetcher) Do(req *http.Request) (*http.Response, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	url := req.URL.String()
+	f.requests = append(f.requests, req.Method+" "+url)
+
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Body:       io.NopCloser(&bytes.Buffer{}),
+		Request:    req,
+	}
+
+	data, ok := f.data[url]
+	if !ok {
+		name, ok := f.files[url]
+		if !ok {
+			return resp, nil
+		}
+
+		var err error
+		data, err = os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp.StatusCode = http.StatusOK
+	if req.Method != http.MethodHead {
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	return resp, nil
+}
+
+// fakeReleaseSource serves GitHub repositories and their releases from memory.
+type fakeReleaseSource struct {
+	repositories map[string]*github.Repository
+	releases     map[string][]*github.RepositoryRelease
+}
+
+func (f *fakeReleaseSource) Get(_ context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	repository, ok := f.repositories[owner+"/"+repo]
+	if !ok {
+		return nil, nil, fmt.Errorf("GET repos/%s/%s: 404 Not Found", owner, repo)
+	}
+
+	return repository, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+}
+
+func (f *fakeReleaseSource) ListReleases(_ context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error) {
+	releases := f.releases[owner+"/"+repo]
+
+	page := max(opts.Page, 1)
+	start := min((page-1)*opts.PerPage, len(releases))
+	end := min(start+opts.PerPage, len(releases))
+
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	if end < len(releases) {
+		resp.NextPage = page + 1
+	}
+
+	return releases[start:end], resp, nil
+}
+
+// addRepository makes a repository with the given releases available.
+func (f *fakeReleaseSource) addRepository(owner, repo string, releases ...*github.RepositoryRelease) {
+	f.repositories[owner+"/"+repo] = &github.Repository{
+		Name:    github.String(repo),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/%s/%s", owner, repo)),
+	}
+	f.releases[owner+"/"+repo] = releases
+}
+
+// fakeRelease describes a GitHub release of mattermost-plugin-demo with the given assets, served
+// by the given fetcher from the named testdata files.
+func fakeRelease(fetcher *fakeBundleFetcher, tag string, updatedAt time.Time, assets map[string]string) *github.RepositoryRelease {
+	release := &github.RepositoryRelease{
+		TagName: github.String(tag),
+		Name:    github.String(tag),
+		HTMLURL: github.String("https://github.com/mattermost/mattermost-plugin-demo/releases/tag/" + tag),
+	}
+
+	for assetName, name := range assets {
+		url := fmt.Sprintf("https://github.com/mattermost/mattermost-plugin-demo/releases/download/%s/%s", tag, assetName)
+		fetcher.serve(url, name)
+
+		release.Assets = append(release.Assets,
+			github.ReleaseAsset{
+				Name:               github.String(assetName),
+				BrowserDownloadURL: github.String(url),
+				UpdatedAt:          &github.Timestamp{Time: updatedAt},
+			},
+			github.ReleaseAsset{
+				Name:               github.String(assetName + ".sig"),
+				BrowserDownloadURL: github.String(url + ".sig"),
+				UpdatedAt:          &github.Timestamp{Time: updatedAt},
+			},
+		)
+	}
+
+	return release
+}
+
+// setupFakes replaces the release source and bundle fetcher with fakes for the duration of the
+// test.
+func setupFakes(t *testing.T) (*fakeReleaseSource, *fakeBundleFetcher) {
+	t.Helper()
+
+	source := &fakeReleaseSource{
+		repositories: make(map[string]*github.Repository),
+		releases:     make(map[string][]*github.RepositoryRelease),
+	}
+	bundles := &fakeBundleFetcher{
+		files: make(map[string]string),
+		data:  make(map[string][]byte),
+	}
+
+	originalNewReleaseSource := newReleaseSource
+	originalFetcher := fetcher
+	t.Cleanup(func() {
+		newReleaseSource = originalNewReleaseSource
+		fetcher = originalFetcher
+	})
+
+	newReleaseSource = func(context.Context, string) releaseSource {
+		return source
+	}
+	fetcher = bundles
+
+	return source, bundles
+}
+
+// resetFlags restores the flags of the given command and its subcommands to their defaults,
+// since cobra keeps parsed values across executions.
+func resetFlags(command *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = sliceValue.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+
+	command.Flags().VisitAll(reset)
+	command.PersistentFlags().VisitAll(reset)
+	for _, subcommand := range command.Commands() {
+		resetFlags(subcommand)
+	}
+}
+
+// runGenerator runs the generator with the given arguments.
+func runGenerator(t *testing.T, args ...string) error {
+	t.Helper()
+
+	resetFlags(generatorCmd)
+	generatorCmd.SetArgs(args)
+	t.Cleanup(func() {
+		generatorCmd.SetArgs(nil)
+	})
+
+	return generatorCmd.Execute()
+}
+
+// writeDatabase writes a database with the given content to a temporary file.
+func writeDatabase(t *testing.T, content string) string {
+	t.Helper()
+
+	dbFile := filepath.Join(t.TempDir(), "plugins.json")
+	require.NoError(t, os.WriteFile(dbFile, []byte(content), 0644))
+
+	return dbFile
+}
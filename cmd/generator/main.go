@@ -5,15 +5,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/blang/semver"
@@ -22,23 +26,27 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/mattermost/mattermost-marketplace/internal/model"
 )
 
 const (
 	defaultRemotePluginStore = "https://plugins.releases.mattermost.com/release"
-	defaultGitHubOrg         = "mattermost"
 )
 
 func init() {
 	generatorCmd.PersistentFlags().Bool("debug", false, "Whether to output debug logs.")
 	generatorCmd.PersistentFlags().String("database", "plugins.json", "Path to the plugins database to update.")
 	generatorCmd.PersistentFlags().String("remote-plugin-store", defaultRemotePluginStore, "Server URL hosting plugin bundles, i.e. from S3.")
+	generatorCmd.PersistentFlags().Bool("dry-run", false, "Perform all downloads and changes, but print a summary of the changes instead of writing the database.")
+	generatorCmd.PersistentFlags().String("output", "", "Path to which to write the updated database. Defaults to the database itself.")
+	generatorCmd.PersistentFlags().StringArray("public-key", nil, "Path to a public key, armored or binary, against which to verify bundle signatures. May be given multiple times.")
 
 	generatorCmd.Flags().Bool("include-pre-release", false, "Whether to include pre-release versions.")
-	generatorCmd.Flags().String("github-org", defaultGitHubOrg, "GitHub organization that owns the plugin releases.")
+	generatorCmd.Flags().String("config", "generator.json", "Path to the config listing the repositories to sync.")
+	generatorCmd.Flags().String("github-org", "", "Only sync the repositories of this GitHub organization from the config.")
+	generatorCmd.Flags().Int("concurrency", 4, "How many repositories, and how many releases of each repository, to sync at once.")
 }
 
 func main() {
@@ -62,11 +70,26 @@ var generatorCmd = &cobra.Command{
 			return err
 		}
 
+		configFile, err := command.Flags().GetString("config")
+		if err != nil {
+			return err
+		}
+
 		githubOrg, err := command.Flags().GetString("github-org")
 		if err != nil {
 			return err
 		}
 
+		config, err := loadConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		repositories, err := config.repositories(githubOrg)
+		if err != nil {
+			return err
+		}
+
 		pluginHost, err := command.Flags().GetString("remote-plugin-store")
 		if err != nil {
 			return err
@@ -78,53 +101,50 @@ var generatorCmd = &cobra.Command{
 		}
 
 		includePreRelease, _ := command.Flags().GetBool("include-pre-release")
-		githubToken := os.Getenv("GITHUB_TOKEN")
-
-		var client *github.Client
 
-		if githubToken != "" {
-			ctx := context.Background()
-			ts := oauth2.StaticTokenSource(
-				&oauth2.Token{AccessToken: githubToken},
-			)
-			tc := oauth2.NewClient(ctx, ts)
-
-			client = github.NewClient(tc)
-		} else {
-			client = github.NewClient(nil)
+		concurrency, _ := command.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return errors.New("concurrency must be at least 1")
 		}
 
 		ctx := context.Background()
+		client := newReleaseSource(ctx, os.Getenv("GITHUB_TOKEN"))
 
-		repositoryNames := []string{
-			"mattermost-plugin-github",
-			"mattermost-plugin-autolink",
-			"mattermost-plugin-zoom",
-			"mattermost-plugin-jira",
-			"mattermost-plugin-welcomebot",
-			"mattermost-plugin-jenkins",
-			"mattermost-plugin-antivirus",
-			"mattermost-plugin-custom-attributes",
-			"mattermost-plugin-aws-SNS",
-			"mattermost-plugin-gitlab",
-			"mattermost-plugin-nps",
-			"mattermost-plugin-webex",
-		}
+		progress := &syncProgress{}
+		start := time.Now()
 
-		plugins := []*model.Plugin{}
+		// Collect the plugins of each repository by index to keep the result deterministic.
+		repositoryPlugins := make([][]*model.Plugin, len(repositories))
 
-		for _, repositoryName := range repositoryNames {
-			logger.Debugf("querying repository %s", repositoryName)
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+		for i, repository := range repositories {
+			g.Go(func() error {
+				logger.Debugf("querying repository %s/%s", repository.Org, repository.Name)
 
-			var releasePlugins []*model.Plugin
-			releasePlugins, err = getReleasePlugins(ctx, client, githubOrg, repositoryName, pluginHost, includePreRelease, existingPlugins)
-			if err != nil {
-				return errors.Wrapf(err, "failed to release plugin for repository %s", repositoryName)
-			}
+				releasePlugins, releaseErr := getReleasePlugins(gctx, client, repository, pluginHost, includePreRelease, existingPlugins, concurrency, progress)
+				if releaseErr != nil {
+					return errors.Wrapf(releaseErr, "failed to release plugin for repository %s/%s", repository.Org, repository.Name)
+				}
+				repositoryPlugins[i] = releasePlugins
+
+				logger.Infof("Synced repository %s/%s (%d of %d)", repository.Org, repository.Name, progress.repositories.Add(1), len(repositories))
 
+				return nil
+			})
+		}
+
+		if err = g.Wait(); err != nil {
+			return err
+		}
+
+		plugins := []*model.Plugin{}
+		for _, releasePlugins := range repositoryPlugins {
 			plugins = append(plugins, releasePlugins...)
 		}
 
+		progress.log(time.Since(start))
+
 		// Ensure mannally added plugin are still keeped in the database
 		manuallyAdded := []*model.Plugin{}
 		for _, ep := range existingPlugins {
@@ -142,7 +162,7 @@ var generatorCmd = &cobra.Command{
 
 		plugins = append(plugins, manuallyAdded...)
 
-		err = pluginsToDatabase(dbFile, plugins)
+		err = saveDatabase(command, dbFile, plugins)
 		if err != nil {
 			return errors.Wrap(err, "failed to write plugins database")
 		}
@@ -151,16 +171,44 @@ var generatorCmd = &cobra.Command{
 	},
 }
 
-// getReleasePlugins queries GitHub for all releases of the given plugin, sorting by plugin version descending.
-func getReleasePlugins(ctx context.Context, client *github.Client, org, repositoryName, pluginHost string, includePreRelease bool, existingPlugins []*model.Plugin) ([]*model.Plugin, error) {
-	logger := logger.WithField("repository", repositoryName)
+// syncProgress counts the outcome of syncing releases across concurrently synced repositories.
+type syncProgress struct {
+	repositories atomic.Int64
+	releases     atomic.Int64
+	downloaded   atomic.Int64
+	unchanged    atomic.Int64
+	skipped      atomic.Int64
+}
 
-	repository, _, err := client.Repositories.Get(ctx, org, repositoryName)
+// log summarizes the progress of a sync that took the given time.
+func (progress *syncProgress) log(elapsed time.Duration) {
+	logger.WithFields(logrus.Fields{
+		"repositories": progress.repositories.Load(),
+		"releases":     progress.releases.Load(),
+		"downloaded":   progress.downloaded.Load(),
+		"unchanged":    progress.unchanged.Load(),
+		"skipped":      progress.skipped.Load(),
+		"elapsed":      elapsed.Round(time.Millisecond).String(),
+	}).Info("Synced releases")
+}
+
+// getReleasePlugins queries GitHub for all releases of the given plugin, sorting by plugin version descending.
+//
+// Up to the given number of releases are inspected concurrently.
+func getReleasePlugins(ctx context.Context, client releaseSource, syncedRepository syncedRepository, pluginHost string, includePreRelease bool, existingPlugins []*model.Plugin, concurrency int, progress *syncProgress) ([]*model.Plugin, error) {
+	logger := logger.WithField("repository", syncedRepository.Name)
+
+	var repository *github.Repository
+	err := withGitHubRetry(ctx, func() error {
+		var getErr error
+		repository, _, getErr = client.Get(ctx, syncedRepository.Org, syncedRepository.Name)
+		return getErr
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get repository")
 	}
 
-	releases, err := getReleases(ctx, client, org, repositoryName, includePreRelease)
+	releases, err := getReleases(ctx, client, syncedRepository, includePreRelease)
 	if err != nil {
 		return nil, err
 	}
@@ -169,47 +217,92 @@ func getReleasePlugins(ctx context.Context, client *github.Client, org, reposito
 		return nil, nil
 	}
 
-	var plugins []*model.Plugin
-	for _, release := range releases {
-		plugin, err := getReleasePlugin(release, repository, existingPlugins, pluginHost)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get release plugin for %s", release.GetName())
-		}
+	releasePlugins := make([]*model.Plugin, len(releases))
 
-		if plugin == nil {
-			logger.Warnf("no plugin found for release %s", release.GetName())
-			continue
-		}
+	g := errgroup.Group{}
+	g.SetLimit(concurrency)
+	for i, release := range releases {
+		g.Go(func() error {
+			progress.releases.Add(1)
 
-		if plugin.Manifest.Version == "" {
-			return nil, errors.Errorf("version is empty for manifest.Id %s", plugin.Manifest.Id)
-		}
+			plugin, releaseErr := getReleasePlugin(release, repository, syncedRepository.Defaults, existingPlugins, pluginHost, progress)
+			if releaseErr != nil {
+				return errors.Wrapf(releaseErr, "failed to get release plugin for %s", release.GetName())
+			}
+
+			if plugin == nil {
+				logger.Warnf("no plugin found for release %s", release.GetName())
+				progress.skipped.Add(1)
+				return nil
+			}
+
+			if plugin.Manifest.Version == "" {
+				return errors.Errorf("version is empty for manifest.Id %s", plugin.Manifest.Id)
+			}
+
+			releasePlugins[i] = plugin
+
+			return nil
+		})
+	}
 
-		plugins = append(plugins, plugin)
+	if err = g.Wait(); err != nil {
+		return nil, err
 	}
 
-	// Sort the final slice by plugin version, descending
+	var plugins []*model.Plugin
+	for _, plugin := range releasePlugins {
+		if plugin != nil {
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	// Sort the final slice by plugin version, descending, then by the most recently updated bundle.
 	sort.SliceStable(
 		plugins,
 		func(i, j int) bool {
-			return semver.MustParse(plugins[i].Manifest.Version).GT(semver.MustParse(plugins[j].Manifest.Version))
+			iVersion := semver.MustParse(plugins[i].Manifest.Version)
+			jVersion := semver.MustParse(plugins[j].Manifest.Version)
+			if iVersion.EQ(jVersion) {
+				return plugins[i].UpdatedAt.After(plugins[j].UpdatedAt)
+			}
+
+			return iVersion.GT(jVersion)
 		},
 	)
 
-	return plugins, nil
+	// A release may repeat the version of an earlier one, e.g. when a tag was cut without bumping
+	// the manifest. Keep only the most recently updated bundle of each version.
+	uniquePlugins := plugins[:0]
+	for _, plugin := range plugins {
+		if n := len(uniquePlugins); n > 0 && uniquePlugins[n-1].Manifest.Id == plugin.Manifest.Id && uniquePlugins[n-1].Manifest.Version == plugin.Manifest.Version {
+			logger.Warnf("ignoring %s, since it repeats version %s of %s", plugin.DownloadURL, plugin.Manifest.Version, uniquePlugins[n-1].DownloadURL)
+			continue
+		}
+
+		uniquePlugins = append(uniquePlugins, plugin)
+	}
+
+	return uniquePlugins, nil
 }
 
-// getReleases returns all GitHub releases for the given repository.
-func getReleases(ctx context.Context, client *github.Client, org, repoName string, includePreRelease bool) ([]*github.RepositoryRelease, error) {
+// getReleases returns all GitHub releases for the given repository with a tag it syncs.
+func getReleases(ctx context.Context, client releaseSource, repository syncedRepository, includePreRelease bool) ([]*github.RepositoryRelease, error) {
 	var result []*github.RepositoryRelease
 	options := &github.ListOptions{
 		Page:    0,
 		PerPage: 40,
 	}
 	for {
-		releases, resp, err := client.Repositories.ListReleases(ctx, org, repoName, options)
+		var releases []*github.RepositoryRelease
+		var resp *github.Response
+		err := withGitHubRetry(ctx, func() error {
+			var listErr error
+			releases, resp, listErr = client.ListReleases(ctx, repository.Org, repository.Name, options)
+			return listErr
+		})
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get releases for repository %s", repoName)
+			return nil, errors.Wrapf(err, "failed to get releases for repository %s", repository.Name)
 		}
 
 		for _, release := range releases {
@@ -221,6 +314,11 @@ func getReleases(ctx context.Context, client *github.Client, org, repoName strin
 				continue
 			}
 
+			if !repository.syncsTag(release.GetTagName()) {
+				logger.Debugf("ignoring release %s excluded by config", release.GetTagName())
+				continue
+			}
+
 			result = append(result, release)
 		}
 
@@ -233,7 +331,7 @@ func getReleases(ctx context.Context, client *github.Client, org, repoName strin
 	return result, nil
 }
 
-func getReleasePlugin(release *github.RepositoryRelease, repository *github.Repository, existingPlugins []*model.Plugin, pluginHost string) (*model.Plugin, error) {
+func getReleasePlugin(release *github.RepositoryRelease, repository *github.Repository, defaults pluginDefaults, existingPlugins []*model.Plugin, pluginHost string, progress *syncProgress) (*model.Plugin, error) {
 	var releaseName string
 	if release.GetName() == "" {
 		releaseName = release.GetTagName()
@@ -289,7 +387,9 @@ func getReleasePlugin(release *github.RepositoryRelease, repository *github.Repo
 	var plugin *model.Plugin
 	for _, p := range existingPlugins {
 		if p.DownloadURL == downloadURL {
-			plugin = p
+			// Copy the entry, since the existing plugins are shared by concurrent syncs.
+			existing := *p
+			plugin = &existing
 			break
 		}
 	}
@@ -308,15 +408,38 @@ func getReleasePlugin(release *github.RepositoryRelease, repository *github.Repo
 		}
 
 		logger.Debugf("fetching download url %s", downloadURL)
+		progress.downloaded.Add(1)
 
+		existingPlugin := plugin
 		plugin = &model.Plugin{}
 		plugin.RepoName = repository.GetName()
 
-		bundleData, err := downloadBundleData(downloadURL)
+		// Keep the metadata of a re-uploaded release, only applying the defaults to new releases.
+		if existingPlugin != nil {
+			plugin.Labels = existingPlugin.Labels
+			plugin.AuthorType = existingPlugin.AuthorType
+			plugin.Hosting = existingPlugin.Hosting
+			plugin.Enterprise = existingPlugin.Enterprise
+			plugin.ReleaseStage = existingPlugin.ReleaseStage
+		} else {
+			defaults.applyTo(plugin)
+		}
+
+		gzBundleData, err := downloadBundle(downloadURL)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed download bundle data for release %s", releaseName)
 		}
 
+		if err = verifier.verify(gzBundleData, signature); err != nil {
+			return nil, errors.Wrapf(err, "failed to verify signature for release %s", releaseName)
+		}
+
+		bundleData, err := decompressBundle(gzBundleData)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress bundle for release %s", releaseName)
+		}
+		plugin.SHA256, plugin.Size = checksumBundle(gzBundleData)
+
 		manifestData, err := getFromTarFile(tar.NewReader(bytes.NewReader(bundleData)), "plugin.json")
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to read manifest from plugin bundle for release %s", releaseName)
@@ -347,6 +470,7 @@ func getReleasePlugin(release *github.RepositoryRelease, repository *github.Repo
 		}
 	} else {
 		logger.Debugf("skipping download since found existing plugin")
+		progress.unchanged.Add(1)
 	}
 
 	if plugin.Manifest == nil {
@@ -404,35 +528,43 @@ func getFromTarFile(reader *tar.Reader, filepath string) ([]byte, error) {
 func downloadSignature(url string) (string, error) {
 	logger.Debugf("fetching signature file from %s", url)
 
-	resp, err := http.Get(url)
+	statusCode, signature, err := fetch(http.MethodGet, url)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to download signature file from %s", url)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.Errorf("received %d status code while downloading plugin bundle from %v", resp.StatusCode, url)
+	if statusCode != http.StatusOK {
+		return "", errors.Errorf("received %d status code while downloading plugin bundle from %v", statusCode, url)
 	}
 
-	signature, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", errors.Wrapf(err, "failed to read signature from %s", url)
-	}
 	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
-func downloadBundleData(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+// downloadBundle returns the gzipped plugin bundle at the given URL.
+func downloadBundle(url string) ([]byte, error) {
+	statusCode, gzBundleData, err := fetch(http.MethodGet, url)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to download plugin bundle from %v", url)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("received %d status code while downloading plugin bundle from %v", resp.StatusCode, url)
+	if statusCode != http.StatusOK {
+		return nil, errors.Errorf("received %d status code while downloading plugin bundle from %v", statusCode, url)
 	}
 
-	gzBundleReader, err := gzip.NewReader(resp.Body)
+	return gzBundleData, nil
+}
+
+// checksumBundle returns the hex-encoded SHA-256 checksum and the size of the given gzipped
+// plugin bundle.
+func checksumBundle(gzBundleData []byte) (string, int64) {
+	checksum := sha256.Sum256(gzBundleData)
+
+	return hex.EncodeToString(checksum[:]), int64(len(gzBundleData))
+}
+
+// decompressBundle returns the tar archive within the given gzipped plugin bundle.
+func decompressBundle(gzBundleData []byte) ([]byte, error) {
+	gzBundleReader, err := gzip.NewReader(bytes.NewReader(gzBundleData))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read gzipped plugin bundle")
 	}
@@ -454,7 +586,7 @@ func getIconDataFromTarFile(file []byte, path string) (string, error) {
 	return fmt.Sprintf("data:image/svg+xml;base64,%s", base64.StdEncoding.EncodeToString(iconData)), nil
 }
 
-// InitCommand parses the log level flag
+// InitCommand parses the log level flag and loads the public keys used to verify signatures.
 func InitCommand(command *cobra.Command, _ []string) error {
 	debug, err := command.Flags().GetBool("debug")
 	if err != nil {
@@ -465,10 +597,39 @@ func InitCommand(command *cobra.Command, _ []string) error {
 		logger.SetLevel(logrus.DebugLevel)
 	}
 
+	publicKeys, err := command.Flags().GetStringArray("public-key")
+	if err != nil {
+		return err
+	}
+
+	verifier = nil
+	if len(publicKeys) > 0 {
+		verifier, err = newSignatureVerifier(publicKeys)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// pluginsFromDatabase reads the plugins of the database at the given path, which must be at the
+// newest schema version so that commands updating it don't mix in entries of an older schema.
 func pluginsFromDatabase(path string) ([]*model.Plugin, error) {
+	database, err := databaseFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if database.SchemaVersion < model.SchemaVersion {
+		return nil, errors.Errorf("database %s has schema version %d, run generator migrate to upgrade it to %d", path, database.SchemaVersion, model.SchemaVersion)
+	}
+
+	return database.Plugins, nil
+}
+
+// databaseFromFile reads the database at the given path at any supported schema version.
+func databaseFromFile(path string) (*model.Database, error) {
 	if path == "" {
 		return nil, errors.New("database name must not be empty")
 	}
@@ -479,19 +640,84 @@ func pluginsFromDatabase(path string) ([]*model.Plugin, error) {
 	}
 	defer file.Close()
 
-	plugins, err := model.PluginsFromReader(file)
+	database, err := model.DatabaseFromReader(file)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read plugins from database %s", path)
 	}
 
-	return plugins, nil
+	return database, nil
+}
+
+// saveDatabase writes the given plugins, updated from the database at the given path, to the
+// path given by --output, defaulting to the database itself.
+//
+// With --dry-run, the changes to the database are printed instead.
+func saveDatabase(command *cobra.Command, dbFile string, plugins []*model.Plugin) error {
+	return saveDatabaseVersion(command, dbFile, model.SchemaVersion, plugins)
+}
+
+// saveDatabaseVersion is saveDatabase for a database at the given schema version.
+func saveDatabaseVersion(command *cobra.Command, dbFile string, schemaVersion int, plugins []*model.Plugin) error {
+	dryRun, err := command.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	output, err := command.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = dbFile
+	}
+
+	if !dryRun {
+		return databaseToFile(output, &model.Database{SchemaVersion: schemaVersion, Plugins: plugins})
+	}
+
+	// Commands may modify the plugins read from the database in place, so compare against the
+	// database as it is on disk.
+	existing, err := databaseFromFile(dbFile)
+	if err != nil {
+		return err
+	}
+
+	diffs := diffDatabases(existing.Plugins, plugins)
+	if err = writeDiffTable(command.OutOrStdout(), diffs); err != nil {
+		return errors.Wrap(err, "failed to write diff")
+	}
+
+	var added, removed, changed int
+	for _, diff := range diffs {
+		added += len(diff.Added)
+		removed += len(diff.Removed)
+		changed += len(diff.Changed)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"added":          added,
+		"removed":        removed,
+		"changed":        changed,
+		"schema_version": schemaVersion,
+	}).Infof("Dry run, not writing %s", output)
+
+	return nil
 }
 
+// pluginsToDatabase writes the given plugins to the database at the given path, at the newest
+// schema version.
 func pluginsToDatabase(path string, plugins []*model.Plugin) error {
+	return databaseToFile(path, &model.Database{SchemaVersion: model.SchemaVersion, Plugins: plugins})
+}
+
+// databaseToFile writes the given database to the given path, sorting its plugins.
+func databaseToFile(path string, database *model.Database) error {
 	if path == "" {
 		return errors.New("database name must not be empty")
 	}
 
+	plugins := database.Plugins
+
 	// Sort plugin before writing to DB.
 	// First ASC by id, then DESC by version.
 	sort.SliceStable(
@@ -508,21 +734,78 @@ func pluginsToDatabase(path string, plugins []*model.Plugin) error {
 		},
 	)
 
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	return writeFileAtomically(path, func(file *os.File) error {
+		err := model.DatabaseToWriter(file, database)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write plugins database %s", path)
+		}
+
+		// Ensure what ends up on disk parses before it replaces the database.
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "failed to rewind written plugins database")
+		}
+
+		written, err := model.DatabaseFromReader(file)
+		if err != nil {
+			return errors.Wrap(err, "failed to read back written plugins database")
+		}
+		if len(written.Plugins) != len(plugins) {
+			return errors.Errorf("read back %d plugins from written plugins database, expected %d", len(written.Plugins), len(plugins))
+		}
+
+		return nil
+	})
+}
+
+// writeFileAtomically replaces the file at the given path with the content written by the given
+// function, preserving the permissions of an existing file.
+//
+// The content is written to a temporary file in the same directory, synced and then renamed into
+// place, so a failure at any point leaves the existing file untouched.
+func writeFileAtomically(path string, write func(file *os.File) error) error {
+	mode := os.FileMode(0644)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return errors.Wrapf(err, "failed to stat %s", path)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return errors.Wrapf(err, "failed to open existing database %s", path)
+		return errors.Wrapf(err, "failed to create temporary file for %s", path)
 	}
-	defer file.Close()
+	tempPath := file.Name()
 
-	_, err = file.Seek(0, 0)
-	if err != nil {
+	success := false
+	defer func() {
+		if !success {
+			file.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	if err = write(file); err != nil {
 		return err
 	}
 
-	err = model.PluginsToWriter(file, plugins)
-	if err != nil {
-		return errors.Wrapf(err, "failed to write plugins database %s", path)
+	if err = file.Chmod(mode); err != nil {
+		return errors.Wrapf(err, "failed to set permissions of %s", tempPath)
+	}
+
+	if err = file.Sync(); err != nil {
+		return errors.Wrapf(err, "failed to sync %s", tempPath)
+	}
+
+	if err = file.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close %s", tempPath)
+	}
+
+	if err = os.Rename(tempPath, path); err != nil {
+		return errors.Wrapf(err, "failed to replace %s", path)
 	}
+	success = true
 
 	return nil
 }
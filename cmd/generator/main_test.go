@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func makePlugin(id, version string) *model.Plugin {
+	return &model.Plugin{
+		HomepageURL: "https://github.com/mattermost/" + id,
+		DownloadURL: "https://plugins.releases.mattermost.com/release/" + id + "-v" + version + ".tar.gz",
+		Signature:   "signature",
+		Manifest: &mattermostModel.Manifest{
+			Id:      id,
+			Name:    id,
+			Version: version,
+		},
+	}
+}
+
+func TestPluginsToDatabase(t *testing.T) {
+	t.Run("shorter database is not left with trailing data", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+
+		plugins := []*model.Plugin{makePlugin("jira", "3.0.0"), makePlugin("jira", "2.0.0"), makePlugin("todo", "0.3.0")}
+		require.NoError(t, pluginsToDatabase(dbFile, plugins))
+
+		require.NoError(t, pluginsToDatabase(dbFile, plugins[:1]))
+
+		written, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, plugins[:1], written)
+	})
+
+	t.Run("sorted by id, then by version descending", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{
+			makePlugin("todo", "0.3.0"),
+			makePlugin("jira", "2.0.0"),
+			makePlugin("jira", "10.0.0"),
+		}))
+
+		written, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, written, 3)
+		assert.Equal(t, "jira", written[0].Manifest.Id)
+		assert.Equal(t, "10.0.0", written[0].Manifest.Version)
+		assert.Equal(t, "2.0.0", written[1].Manifest.Version)
+		assert.Equal(t, "todo", written[2].Manifest.Id)
+	})
+
+	t.Run("new database", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("jira", "3.0.0")}))
+
+		info, err := os.Stat(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	})
+
+	t.Run("permissions are preserved", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, os.WriteFile(dbFile, []byte("[]"), 0600))
+
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("jira", "3.0.0")}))
+
+		info, err := os.Stat(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("no temporary files are left behind", func(t *testing.T) {
+		dir := t.TempDir()
+		dbFile := filepath.Join(dir, "plugins.json")
+
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{makePlugin("jira", "3.0.0")}))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "plugins.json", entries[0].Name())
+	})
+
+	t.Run("failed write leaves the database untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		dbFile := filepath.Join(dir, "plugins.json")
+		require.NoError(t, os.WriteFile(dbFile, []byte("[]"), 0644))
+
+		err := writeFileAtomically(dbFile, func(file *os.File) error {
+			_, err := file.WriteString(`[{"manifest":`)
+			require.NoError(t, err)
+
+			return os.ErrInvalid
+		})
+		require.ErrorIs(t, err, os.ErrInvalid)
+
+		data, err := os.ReadFile(dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		err := pluginsToDatabase("", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not be empty")
+	})
+}
+
+func TestSync(t *testing.T) {
+	source, bundles := setupFakes(t)
+
+	updatedAt := time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)
+	source.addRepository("mattermost", "mattermost-plugin-demo",
+		&github.RepositoryRelease{TagName: github.String("v0.3.0"), Draft: github.Bool(true)},
+		&github.RepositoryRelease{TagName: github.String("v0.3.0-rc1"), Prerelease: github.Bool(true)},
+		fakeRelease(bundles, "v0.2.0", updatedAt, map[string]string{
+			"com.mattermost.demo-plugin-0.2.0.tar.gz":             "mattermost-plugin-demo-v0.2.0.tar.gz",
+			"com.mattermost.demo-plugin-0.2.0-linux-amd64.tar.gz": "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz",
+		}),
+		// Repeats version 0.1.0 with an older bundle, so it must be ignored.
+		fakeRelease(bundles, "v0.1.1", updatedAt.Add(-time.Hour), map[string]string{
+			"com.mattermost.demo-plugin-0.1.0.tar.gz": "mattermost-plugin-demo-v0.1.0.tar.gz",
+		}),
+		fakeRelease(bundles, "v0.1.0", updatedAt, map[string]string{
+			"com.mattermost.demo-plugin-0.1.0.tar.gz": "mattermost-plugin-demo-v0.1.0.tar.gz",
+		}),
+		// Excluded by the config, so its missing assets must not be fetched.
+		&github.RepositoryRelease{TagName: github.String("v0.0.1")},
+	)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	configFile := writeConfig(t, `{
+		"version": 1,
+		"orgs": [
+			{
+				"name": "mattermost",
+				"defaults": {"author_type": "mattermost", "release_stage": "production"},
+				"repos": [
+					{"name": "mattermost-plugin-demo", "defaults": {"hosting": "on-prem"}, "exclude_tags": ["v0.0.*"]}
+				]
+			}
+		]
+	}`)
+
+	existingDemo := makePlugin("com.mattermost.demo-plugin", "0.1.0")
+	existingDemo.DownloadURL = "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz"
+	existingDemo.AuthorType = model.Partner
+	existingDemo.UpdatedAt = updatedAt
+	manuallyAdded := makePlugin("com.example.manual", "1.0.0")
+
+	dbFile := filepath.Join(t.TempDir(), "plugins.json")
+	require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{existingDemo, manuallyAdded}))
+
+	err := runGenerator(t, "--database", dbFile, "--config", configFile, "--remote-plugin-store", fakePluginHost, "--concurrency", "2")
+	require.NoError(t, err)
+
+	plugins, err := pluginsFromDatabase(dbFile)
+	require.NoError(t, err)
+	require.Len(t, plugins, 3)
+
+	t.Run("new release", func(t *testing.T) {
+		plugin := plugins[1]
+		require.Equal(t, "com.mattermost.demo-plugin", plugin.Manifest.Id)
+		require.Equal(t, "0.2.0", plugin.Manifest.Version)
+
+		assert.Equal(t, "mattermost-plugin-demo", plugin.RepoName)
+		assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-demo", plugin.HomepageURL)
+		assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.2.0/com.mattermost.demo-plugin-0.2.0.tar.gz", plugin.DownloadURL)
+		assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-demo/releases/tag/v0.2.0", plugin.ReleaseNotesURL)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("signature of mattermost-plugin-demo-v0.2.0.tar.gz\n")), plugin.Signature)
+		assert.True(t, strings.HasPrefix(plugin.IconData, "data:image/svg+xml;base64,"))
+		assert.Equal(t, updatedAt, plugin.UpdatedAt)
+
+		assert.Equal(t, model.Mattermost, plugin.AuthorType)
+		assert.Equal(t, model.OnPrem, plugin.Hosting)
+		assert.Equal(t, model.Production, plugin.ReleaseStage)
+
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", plugin.Platforms.LinuxAmd64.DownloadURL)
+		assert.NotEmpty(t, plugin.Platforms.LinuxAmd64.Signature)
+		assert.Empty(t, plugin.Platforms.DarwinAmd64)
+
+		checksum, size := testdataChecksum(t, "mattermost-plugin-demo-v0.2.0.tar.gz")
+		assert.Equal(t, checksum, plugin.SHA256)
+		assert.Equal(t, size, plugin.Size)
+
+		checksum, size = testdataChecksum(t, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+		assert.Equal(t, checksum, plugin.Platforms.LinuxAmd64.SHA256)
+		assert.Equal(t, size, plugin.Platforms.LinuxAmd64.Size)
+	})
+
+	t.Run("existing release is kept without downloading", func(t *testing.T) {
+		plugin := plugins[2]
+		require.Equal(t, "0.1.0", plugin.Manifest.Version)
+		assert.Equal(t, model.Partner, plugin.AuthorType)
+		assert.NotContains(t, bundles.requests, "GET "+existingDemo.DownloadURL)
+	})
+
+	t.Run("release repeating a version is ignored", func(t *testing.T) {
+		for _, plugin := range plugins {
+			assert.NotContains(t, plugin.DownloadURL, "/v0.1.1/")
+		}
+	})
+
+	t.Run("manually added plugin is kept", func(t *testing.T) {
+		assert.Equal(t, manuallyAdded.Manifest.Id, plugins[0].Manifest.Id)
+	})
+
+	t.Run("unknown org", func(t *testing.T) {
+		err := runGenerator(t, "--database", dbFile, "--config", configFile, "--github-org", "unknown")
+		require.Error(t, err)
+	})
+}
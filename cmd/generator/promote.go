@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func init() {
+	generatorCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().String("to", "", "The release stage to move the release to, one of production, beta or experimental.")
+	promoteCmd.Flags().Bool("cascade", false, "Also move all earlier releases of the plugin to the release stage")
+	_ = promoteCmd.MarkFlagRequired("to")
+}
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote <id> <version>",
+	Short: "Move a plugin release to another release stage",
+	Long: "The promote command moves the given release of a plugin to the release stage given by --to, e.g. from beta to production, " +
+		"and records when it did so. With --cascade, all earlier releases of the plugin are moved as well.\n\n" +
+		"The labels served with each release are derived from its release stage, so the change is reflected as soon as the " +
+		"database is deployed.",
+	Example: `  generator promote com.mattermost.plugin-todo 0.3.0 --to production
+  generator promote com.mattermost.plugin-todo 0.3.0 --to production --cascade`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		id := args[0]
+		version, err := semver.Parse(strings.TrimPrefix(args[1], "v"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse version %s", args[1])
+		}
+
+		to, err := command.Flags().GetString("to")
+		if err != nil {
+			return err
+		}
+
+		releaseStage := model.ReleaseStage(to)
+		if !slices.Contains([]model.ReleaseStage{model.Production, model.Beta, model.Experimental}, releaseStage) {
+			return errors.Errorf("unknown release stage %q, expected production, beta or experimental", to)
+		}
+
+		cascade, err := command.Flags().GetBool("cascade")
+		if err != nil {
+			return err
+		}
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		now := time.Now().In(time.UTC)
+		found := false
+		promoted := 0
+		for _, plugin := range plugins {
+			if plugin.Manifest.Id != id {
+				continue
+			}
+
+			pluginVersion, err := semver.Parse(plugin.Manifest.Version)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse version of release %s of plugin %s", plugin.Manifest.Version, id)
+			}
+
+			isRelease := pluginVersion.EQ(version)
+			if !isRelease && !(cascade && pluginVersion.LT(version)) {
+				continue
+			}
+			found = found || isRelease
+
+			if promote(plugin, releaseStage, now) {
+				promoted++
+			}
+		}
+
+		if !found {
+			return errors.Errorf("plugin %s has no release %s", id, version)
+		}
+
+		if promoted == 0 {
+			logger.Infof("Releases of plugin %s are already %s", id, releaseStage)
+			return nil
+		}
+
+		err = saveDatabase(command, dbFile, plugins)
+		if err != nil {
+			return errors.Wrap(err, "failed to write plugins database")
+		}
+
+		return nil
+	},
+}
+
+// promote moves the given plugin to the given release stage, recording the given time as that of
+// the promotion. It reports whether the release stage changed.
+func promote(plugin *model.Plugin, releaseStage model.ReleaseStage, now time.Time) bool {
+	if plugin.ReleaseStage == releaseStage {
+		return false
+	}
+
+	logger.Infof("Moving release %s of plugin %s from %s to %s", plugin.Manifest.Version, plugin.Manifest.Id, plugin.ReleaseStage, releaseStage)
+
+	plugin.ReleaseStage = releaseStage
+	plugin.PromotedAt = &now
+
+	return true
+}
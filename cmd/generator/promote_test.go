@@ -0,0 +1,106 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestPromote(t *testing.T) {
+	// setupDatabase writes a database holding three beta releases of a plugin and a beta release
+	// of another plugin.
+	setupDatabase := func(t *testing.T) string {
+		t.Helper()
+
+		var plugins []*model.Plugin
+		for _, release := range [][2]string{{"com.example.a", "1.2.0"}, {"com.example.a", "1.1.0"}, {"com.example.a", "1.0.0"}, {"com.example.b", "1.0.0"}} {
+			plugin := makePlugin(release[0], release[1])
+			plugin.AuthorType = model.Mattermost
+			plugin.ReleaseStage = model.Beta
+			plugins = append(plugins, plugin)
+		}
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, plugins))
+
+		return dbFile
+	}
+
+	readDatabase := func(t *testing.T, dbFile string) []*model.Plugin {
+		t.Helper()
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+		require.Len(t, plugins, 4)
+
+		return plugins
+	}
+
+	t.Run("single release", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "promote", "com.example.a", "v1.1.0", "--to", "production", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins := readDatabase(t, dbFile)
+		assert.Equal(t, model.Beta, plugins[0].ReleaseStage)
+		assert.Nil(t, plugins[0].PromotedAt)
+		assert.Equal(t, model.Production, plugins[1].ReleaseStage)
+		require.NotNil(t, plugins[1].PromotedAt)
+		assert.WithinDuration(t, time.Now(), *plugins[1].PromotedAt, time.Minute)
+		assert.Equal(t, model.Beta, plugins[2].ReleaseStage)
+		assert.Equal(t, model.Beta, plugins[3].ReleaseStage)
+
+		plugins[1].AddLabels()
+		assert.NotContains(t, plugins[1].Labels, model.BetaLabel)
+		plugins[2].AddLabels()
+		assert.Contains(t, plugins[2].Labels, model.BetaLabel)
+	})
+
+	t.Run("cascade to earlier releases", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "promote", "com.example.a", "1.1.0", "--to", "production", "--cascade", "--database", dbFile)
+		require.NoError(t, err)
+
+		plugins := readDatabase(t, dbFile)
+		assert.Equal(t, model.Beta, plugins[0].ReleaseStage)
+		assert.Equal(t, model.Production, plugins[1].ReleaseStage)
+		assert.Equal(t, model.Production, plugins[2].ReleaseStage)
+		assert.NotNil(t, plugins[2].PromotedAt)
+		assert.Equal(t, model.Beta, plugins[3].ReleaseStage)
+	})
+
+	t.Run("release already in stage", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "promote", "com.example.a", "1.0.0", "--to", "beta", "--database", dbFile)
+		require.NoError(t, err)
+		assert.Nil(t, readDatabase(t, dbFile)[2].PromotedAt)
+	})
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{"unknown release stage", []string{"com.example.a", "1.0.0", "--to", "stable"}, `unknown release stage "stable", expected production, beta or experimental`},
+		{"unknown release", []string{"com.example.a", "2.0.0", "--to", "production"}, "plugin com.example.a has no release 2.0.0"},
+		{"unknown plugin", []string{"com.example.c", "1.0.0", "--to", "production", "--cascade"}, "plugin com.example.c has no release 1.0.0"},
+		{"missing stage", []string{"com.example.a", "1.0.0"}, `required flag(s) "to" not set`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			dbFile := setupDatabase(t)
+
+			err := runGenerator(t, append(append([]string{"promote"}, testCase.args...), "--database", dbFile)...)
+			require.EqualError(t, err, testCase.expected)
+		})
+	}
+}
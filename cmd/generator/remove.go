@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func init() {
+	generatorCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().Bool("all", false, "Remove every release of the plugin")
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <id> <version|--all>",
+	Short: "Remove plugin releases from the plugins.json database",
+	Long: "The remove command removes the given release of a plugin from the database, or every release of the " +
+		"plugin with --all. The version may be given with or without a leading v.",
+	Example: `  generator remove com.mattermost.plugin-todo 0.3.0
+  generator remove com.mattermost.plugin-todo --all`,
+	Args: func(command *cobra.Command, args []string) error {
+		if all, _ := command.Flags().GetBool("all"); all {
+			return cobra.ExactArgs(1)(command, args)
+		}
+
+		return cobra.ExactArgs(2)(command, args)
+	},
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		id := args[0]
+		var version string
+		if len(args) > 1 {
+			version = strings.TrimPrefix(args[1], "v")
+		}
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		removed := 0
+		plugins = slices.DeleteFunc(plugins, func(plugin *model.Plugin) bool {
+			if !matchesRelease(plugin, id, version) {
+				return false
+			}
+
+			logger.Infof("Removing release %s of plugin %s at %s", plugin.Manifest.Version, plugin.Manifest.Id, plugin.DownloadURL)
+			removed++
+
+			return true
+		})
+
+		if removed == 0 {
+			if version == "" {
+				return errors.Errorf("plugin %s has no releases", id)
+			}
+
+			return errors.Errorf("plugin %s has no release %s", id, version)
+		}
+
+		err = saveDatabase(command, dbFile, plugins)
+		if err != nil {
+			return errors.Wrap(err, "failed to write plugins database")
+		}
+
+		return nil
+	},
+}
+
+// matchesRelease reports whether the given plugin is a release of the plugin with the given id, and
+// of the given version unless empty.
+func matchesRelease(plugin *model.Plugin, id, version string) bool {
+	return plugin.Manifest.Id == id && (version == "" || plugin.Manifest.Version == version)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestRemove(t *testing.T) {
+	setupDatabase := func(t *testing.T) string {
+		t.Helper()
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{
+			makePlugin("com.example.a", "1.1.0"),
+			makePlugin("com.example.a", "1.0.0"),
+			makePlugin("com.example.b", "1.0.0"),
+		}))
+
+		return dbFile
+	}
+
+	// releases lists the id and version of each release in the given database.
+	releases := func(t *testing.T, dbFile string) []string {
+		t.Helper()
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		require.NoError(t, err)
+
+		var releases []string
+		for _, plugin := range plugins {
+			releases = append(releases, plugin.Manifest.Id+"@"+plugin.Manifest.Version)
+		}
+
+		return releases
+	}
+
+	t.Run("version", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "remove", "com.example.a", "v1.1.0", "--database", dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"com.example.a@1.0.0", "com.example.b@1.0.0"}, releases(t, dbFile))
+	})
+
+	t.Run("all versions", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "remove", "com.example.a", "--all", "--database", dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"com.example.b@1.0.0"}, releases(t, dbFile))
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "remove", "com.example.a", "--database", dbFile)
+		require.Error(t, err)
+
+		err = runGenerator(t, "remove", "com.example.a", "1.0.0", "--all", "--database", dbFile)
+		require.Error(t, err)
+		assert.Len(t, releases(t, dbFile), 3)
+	})
+
+	t.Run("unknown release", func(t *testing.T) {
+		dbFile := setupDatabase(t)
+
+		err := runGenerator(t, "remove", "com.example.a", "2.0.0", "--database", dbFile)
+		require.EqualError(t, err, "plugin com.example.a has no release 2.0.0")
+
+		err = runGenerator(t, "remove", "com.example.c", "--all", "--database", dbFile)
+		require.EqualError(t, err, "plugin com.example.c has no releases")
+		assert.Len(t, releases(t, dbFile), 3)
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/pkg/errors"
+)
+
+var (
+	// retryAttempts is the number of times a request failing transiently is attempted.
+	retryAttempts = 4
+	// retryBaseDelay is the delay before the first retry, doubling with each further retry.
+	retryBaseDelay = time.Second
+
+	// gitHubRetryAttempts is the number of times a rate-limited GitHub API call is attempted.
+	gitHubRetryAttempts = 3
+	// defaultAbuseRateLimitDelay is how long to wait on a secondary rate limit without a Retry-After.
+	defaultAbuseRateLimitDelay = time.Minute
+)
+
+// fetch requests the given URL, returning the status code and body of the response.
+//
+// Network errors and 5xx or 429 responses are retried with exponential backoff. The status code
+// of the last attempt is returned rather than treated as an error, leaving its interpretation to
+// the caller.
+func fetch(method, url string) (int, []byte, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		statusCode, body, err := fetchOnce(method, url)
+		if !isTransientFailure(statusCode, err) || attempt >= retryAttempts {
+			return statusCode, body, err
+		}
+
+		logger.WithError(err).WithField("status_code", statusCode).Warnf("%s %s failed, retrying in %s (attempt %d of %d)", method, url, delay, attempt, retryAttempts)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func fetchOnce(method, url string) (int, []byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed to create request for %s", url)
+	}
+
+	resp, err := fetcher.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, errors.Wrapf(err, "failed to read response from %s", url)
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// isTransientFailure reports whether a request with the given outcome is worth retrying.
+func isTransientFailure(statusCode int, err error) bool {
+	return err != nil || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// withGitHubRetry invokes the given GitHub API call, waiting out rate limits before retrying.
+func withGitHubRetry(ctx context.Context, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+
+		delay, rateLimited := rateLimitDelay(err, time.Now())
+		if !rateLimited || attempt >= gitHubRetryAttempts {
+			return err
+		}
+
+		logger.WithError(err).Warnf("GitHub rate limit exceeded, retrying in %s (attempt %d of %d)", delay, attempt, gitHubRetryAttempts)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// rateLimitDelay returns how long to wait before retrying a GitHub API call failing with the
+// given error, if it failed due to a rate limit.
+func rateLimitDelay(err error, now time.Time) (time.Duration, bool) {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		// Allow for some clock skew past the advertised reset.
+		return max(rateLimitErr.Rate.Reset.Time.Sub(now), 0) + time.Second, true
+	}
+
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseRateLimitErr) {
+		if abuseRateLimitErr.RetryAfter != nil {
+			return *abuseRateLimitErr.RetryAfter, true
+		}
+
+		return defaultAbuseRateLimitDelay, true
+	}
+
+	return 0, false
+}
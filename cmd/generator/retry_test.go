@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingServer responds with the given status codes in turn, then with 200 OK and the given body.
+func failingServer(t *testing.T, body string, statusCodes ...int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var requests atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := requests.Add(1)
+		if int(request) <= len(statusCodes) {
+			w.WriteHeader(statusCodes[request-1])
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+func TestFetch(t *testing.T) {
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = originalDelay })
+
+	t.Run("success", func(t *testing.T) {
+		ts, requests := failingServer(t, "bundle")
+
+		statusCode, body, err := fetch(http.MethodGet, ts.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "bundle", string(body))
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("transient failures are retried", func(t *testing.T) {
+		ts, requests := failingServer(t, "bundle", http.StatusBadGateway, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+		statusCode, body, err := fetch(http.MethodGet, ts.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "bundle", string(body))
+		assert.EqualValues(t, 4, requests.Load())
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		ts, requests := failingServer(t, "bundle", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+		statusCode, _, err := fetch(http.MethodGet, ts.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, statusCode)
+		assert.EqualValues(t, retryAttempts, requests.Load())
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		ts, requests := failingServer(t, "bundle", http.StatusNotFound)
+
+		statusCode, _, err := fetch(http.MethodHead, ts.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, statusCode)
+		assert.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("network errors are retried", func(t *testing.T) {
+		ts, _ := failingServer(t, "bundle")
+		url := ts.URL
+		ts.Close()
+
+		_, _, err := fetch(http.MethodGet, url)
+		require.Error(t, err)
+	})
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	retryAfter := 30 * time.Second
+
+	for _, tc := range []struct {
+		name                string
+		err                 error
+		expectedDelay       time.Duration
+		expectedRateLimited bool
+	}{
+		{"no error", nil, 0, false},
+		{"other error", errors.New("failed"), 0, false},
+		{
+			"rate limit",
+			&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(time.Minute)}}},
+			time.Minute + time.Second,
+			true,
+		},
+		{
+			"rate limit already reset",
+			&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(-time.Minute)}}},
+			time.Second,
+			true,
+		},
+		{
+			"wrapped rate limit",
+			errors.Wrap(&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(time.Minute)}}}, "failed"),
+			time.Minute + time.Second,
+			true,
+		},
+		{"abuse rate limit with retry after", &github.AbuseRateLimitError{RetryAfter: &retryAfter}, retryAfter, true},
+		{"abuse rate limit without retry after", &github.AbuseRateLimitError{}, defaultAbuseRateLimitDelay, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			delay, rateLimited := rateLimitDelay(tc.err, now)
+			assert.Equal(t, tc.expectedDelay, delay)
+			assert.Equal(t, tc.expectedRateLimited, rateLimited)
+		})
+	}
+}
+
+func TestWithGitHubRetry(t *testing.T) {
+	retryAfter := time.Millisecond
+	rateLimitErr := &github.AbuseRateLimitError{RetryAfter: &retryAfter}
+
+	t.Run("retries rate limited calls", func(t *testing.T) {
+		calls := 0
+		err := withGitHubRetry(context.Background(), func() error {
+			calls++
+			if calls == 1 {
+				return rateLimitErr
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		calls := 0
+		err := withGitHubRetry(context.Background(), func() error {
+			calls++
+			return rateLimitErr
+		})
+		require.ErrorIs(t, err, rateLimitErr)
+		assert.Equal(t, gitHubRetryAttempts, calls)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		calls := 0
+		failure := errors.New("not found")
+		err := withGitHubRetry(context.Background(), func() error {
+			calls++
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		longRetryAfter := time.Hour
+		err := withGitHubRetry(ctx, func() error {
+			return &github.AbuseRateLimitError{RetryAfter: &longRetryAfter}
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
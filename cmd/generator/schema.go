@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// schemaMigration upgrades a single plugin entry by one schema version.
+//
+// Entries failing to migrate are kept at their previous schema version, while the others are
+// written, so running a migration again on an already migrated entry must leave it unchanged.
+type schemaMigration struct {
+	name    string
+	migrate func(plugin *model.Plugin, pluginHost string) error
+}
+
+// schemaMigrations upgrades the database from schema version i to i+1 at index i, and so must
+// hold exactly model.SchemaVersion migrations.
+var schemaMigrations = []schemaMigration{
+	{name: "labels-to-metadata", migrate: migrateLabelsToMetadata},
+	{name: "platform-bundles", migrate: migratePlatformBundles},
+}
+
+// migrateSchema applies the migrations upgrading the given plugin from schema version from to
+// schema version to.
+func migrateSchema(plugin *model.Plugin, pluginHost string, from, to int) error {
+	for _, migration := range schemaMigrations[from:to] {
+		if err := migration.migrate(plugin, pluginHost); err != nil {
+			return errors.Wrapf(err, "failed to apply migration %s", migration.name)
+		}
+	}
+
+	return nil
+}
+
+// migrateLabelsToMetadata replaces the community, enterprise and beta labels, since derived from
+// metadata, with the author type and release stage of the plugin.
+func migrateLabelsToMetadata(plugin *model.Plugin, _ string) error {
+	var newLabels []model.Label
+	for _, l := range plugin.Labels {
+		switch l {
+		case model.EnterpriseLabel:
+			// Just drop it
+		case model.CommunityLabel:
+			plugin.AuthorType = model.Community
+		case model.BetaLabel:
+			plugin.ReleaseStage = model.Beta
+		default:
+			// Keep other labels
+			newLabels = append(newLabels, l)
+		}
+	}
+	plugin.Labels = newLabels
+
+	if plugin.AuthorType == "" {
+		plugin.AuthorType = model.Mattermost
+	}
+
+	if plugin.ReleaseStage == "" {
+		plugin.ReleaseStage = model.Production
+	}
+
+	return nil
+}
+
+// migratePlatformBundles verifies the signature of the plugin and adds its platform-specific
+// bundles.
+func migratePlatformBundles(plugin *model.Plugin, pluginHost string) error {
+	if verifier != nil {
+		// Entries predating signed releases are migrated without verification.
+		if plugin.Signature == "" {
+			logger.Warnf("skipping signature verification of unsigned plugin %s-%s", plugin.Manifest.Id, plugin.Manifest.Version)
+		} else if err := verifier.verifyRemote(plugin.DownloadURL, plugin.Signature); err != nil {
+			return errors.Wrapf(err, "failed to verify signature of plugin %s-%s", plugin.Manifest.Id, plugin.Manifest.Version)
+		}
+	}
+
+	_, err := addPlatformSpecificBundles(plugin, pluginHost)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add platform-specific bundles for plugin %s-%s", plugin.Manifest.Id, plugin.Manifest.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func TestSchemaMigrations(t *testing.T) {
+	require.Len(t, schemaMigrations, model.SchemaVersion, "every schema version must have a migration")
+
+	names := map[string]bool{}
+	for _, migration := range schemaMigrations {
+		assert.False(t, names[migration.name], "migration %s is defined more than once", migration.name)
+		names[migration.name] = true
+	}
+}
+
+func TestMigrateLabelsToMetadata(t *testing.T) {
+	otherLabel := model.Label{Name: "Other"}
+
+	t.Run("labels", func(t *testing.T) {
+		plugin := makePlugin("jira", "3.0.0")
+		plugin.Labels = []model.Label{model.CommunityLabel, model.EnterpriseLabel, model.BetaLabel, otherLabel}
+
+		require.NoError(t, migrateLabelsToMetadata(plugin, fakePluginHost))
+		assert.Equal(t, []model.Label{otherLabel}, plugin.Labels)
+		assert.Equal(t, model.Community, plugin.AuthorType)
+		assert.Equal(t, model.Beta, plugin.ReleaseStage)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		plugin := makePlugin("jira", "3.0.0")
+
+		require.NoError(t, migrateLabelsToMetadata(plugin, fakePluginHost))
+		assert.Empty(t, plugin.Labels)
+		assert.Equal(t, model.Mattermost, plugin.AuthorType)
+		assert.Equal(t, model.Production, plugin.ReleaseStage)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		plugin := makePlugin("jira", "3.0.0")
+		plugin.Labels = []model.Label{model.CommunityLabel, otherLabel}
+
+		require.NoError(t, migrateLabelsToMetadata(plugin, fakePluginHost))
+		migrated := *plugin
+
+		require.NoError(t, migrateLabelsToMetadata(plugin, fakePluginHost))
+		assert.Equal(t, &migrated, plugin)
+	})
+}
+
+func TestMigratePlatformBundles(t *testing.T) {
+	_, bundles := setupFakes(t)
+	bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	t.Run("platform bundles", func(t *testing.T) {
+		plugin := makePlugin("com.mattermost.demo-plugin", "0.2.0")
+		plugin.Signature = ""
+		plugin.RepoName = "mattermost-plugin-demo"
+		plugin.Manifest.Server = &mattermostModel.ManifestServer{
+			Executables: map[string]string{model.LinuxAmd64: "server/dist/plugin-linux-amd64"},
+		}
+
+		require.NoError(t, migratePlatformBundles(plugin, fakePluginHost))
+		assert.Equal(t, fakePluginHost+"/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz", plugin.Platforms.LinuxAmd64.DownloadURL)
+		assert.NotEmpty(t, plugin.Platforms.LinuxAmd64.SHA256)
+		assert.Empty(t, plugin.Platforms.DarwinAmd64)
+		assert.Empty(t, plugin.Platforms.WindowsAmd64)
+
+		migrated := *plugin
+		require.NoError(t, migratePlatformBundles(plugin, fakePluginHost))
+		assert.Equal(t, &migrated, plugin)
+	})
+
+	t.Run("without repository", func(t *testing.T) {
+		plugin := makePlugin("jira", "3.0.0")
+		plugin.Signature = ""
+
+		require.NoError(t, migratePlatformBundles(plugin, fakePluginHost))
+		assert.Empty(t, plugin.Platforms)
+	})
+
+	t.Run("unknown signer", func(t *testing.T) {
+		signer := newTestSigner(t)
+		publicKey := signer.writePublicKey(t, true)
+		bundles.serve(fakePluginHost+"/mattermost-plugin-demo-v0.2.0.tar.gz", "mattermost-plugin-demo-v0.2.0.tar.gz")
+
+		var err error
+		verifier, err = newSignatureVerifier([]string{publicKey})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			verifier = nil
+		})
+
+		plugin := makePlugin("com.mattermost.demo-plugin", "0.2.0")
+		plugin.DownloadURL = fakePluginHost + "/mattermost-plugin-demo-v0.2.0.tar.gz"
+		plugin.Signature = "c2lnbmF0dXJl"
+
+		err = migratePlatformBundles(plugin, fakePluginHost)
+		require.ErrorContains(t, err, "failed to verify signature of plugin com.mattermost.demo-plugin-0.2.0")
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+
+	"github.com/pkg/errors"
+	// The Mattermost server verifies plugin signatures with the same OpenPGP implementation.
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+)
+
+// signatureVerifier checks the signatures of plugin bundles against a set of trusted public keys.
+type signatureVerifier struct {
+	keyring openpgp.EntityList
+}
+
+// verifier verifies the signatures of the bundles handled by the generator. It is nil unless
+// public keys are configured with --public-key, leaving verification disabled.
+var verifier *signatureVerifier
+
+// newSignatureVerifier loads the armored or binary public keys at the given paths.
+func newSignatureVerifier(paths []string) (*signatureVerifier, error) {
+	keyring := openpgp.EntityList{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read public key %s", path)
+		}
+
+		var entities openpgp.EntityList
+		if isArmored(data) {
+			entities, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		} else {
+			entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse public key %s", path)
+		}
+
+		keyring = append(keyring, entities...)
+	}
+
+	return &signatureVerifier{keyring: keyring}, nil
+}
+
+// verify checks the given base64 encoded signature, armored or binary, of the given gzipped
+// bundle. A nil verifier accepts any signature.
+func (v *signatureVerifier) verify(gzBundleData []byte, signature string) error {
+	if v == nil {
+		return nil
+	}
+
+	if signature == "" {
+		return errors.New("bundle is not signed")
+	}
+
+	signatureData, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode signature")
+	}
+
+	if isArmored(signatureData) {
+		_, err = openpgp.CheckArmoredDetachedSignature(v.keyring, bytes.NewReader(gzBundleData), bytes.NewReader(signatureData))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(v.keyring, bytes.NewReader(gzBundleData), bytes.NewReader(signatureData))
+	}
+	if err != nil {
+		return errors.Wrap(err, "signature does not match any public key")
+	}
+
+	return nil
+}
+
+// verifyRemote downloads the bundle at the given URL to check the given base64 encoded signature.
+// A nil verifier accepts any signature without downloading the bundle.
+func (v *signatureVerifier) verifyRemote(url, signature string) error {
+	if v == nil {
+		return nil
+	}
+
+	if signature == "" {
+		return errors.New("bundle is not signed")
+	}
+
+	gzBundleData, err := downloadBundle(url)
+	if err != nil {
+		return err
+	}
+
+	return v.verify(gzBundleData, signature)
+}
+
+// isArmored reports whether the given OpenPGP data is ASCII armored rather than binary.
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"       //nolint:staticcheck
+	"golang.org/x/crypto/openpgp/armor" //nolint:staticcheck
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// testSigner signs bundles with a key generated for the test.
+type testSigner struct {
+	entity *openpgp.Entity
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+
+	return &testSigner{entity: entity}
+}
+
+// writePublicKey writes the public key of the signer to a temporary file, armored if requested.
+func (s *testSigner) writePublicKey(t *testing.T, armored bool) string {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	if armored {
+		w, err := armor.Encode(buffer, openpgp.PublicKeyType, nil)
+		require.NoError(t, err)
+		require.NoError(t, s.entity.Serialize(w))
+		require.NoError(t, w.Close())
+	} else {
+		require.NoError(t, s.entity.Serialize(buffer))
+	}
+
+	path := filepath.Join(t.TempDir(), "public-key")
+	require.NoError(t, os.WriteFile(path, buffer.Bytes(), 0600))
+
+	return path
+}
+
+// sign returns the detached signature of the given data, armored if requested.
+func (s *testSigner) sign(t *testing.T, data []byte, armored bool) []byte {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	if armored {
+		require.NoError(t, openpgp.ArmoredDetachSign(buffer, s.entity, bytes.NewReader(data), nil))
+	} else {
+		require.NoError(t, openpgp.DetachSign(buffer, s.entity, bytes.NewReader(data), nil))
+	}
+
+	return buffer.Bytes()
+}
+
+// signTestdata returns the base64 encoded signature of the given testdata file.
+func (s *testSigner) signTestdata(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(s.sign(t, data, false))
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	signer := newTestSigner(t)
+	otherSigner := newTestSigner(t)
+
+	bundle := []byte("bundle")
+
+	t.Run("nil verifier accepts any signature", func(t *testing.T) {
+		var v *signatureVerifier
+		assert.NoError(t, v.verify(bundle, ""))
+		assert.NoError(t, v.verifyRemote("https://example.com/missing.tar.gz", ""))
+	})
+
+	t.Run("missing public key", func(t *testing.T) {
+		_, err := newSignatureVerifier([]string{filepath.Join(t.TempDir(), "missing")})
+		require.Error(t, err)
+	})
+
+	for _, armoredKey := range []bool{true, false} {
+		for _, armoredSignature := range []bool{true, false} {
+			v, err := newSignatureVerifier([]string{otherSigner.writePublicKey(t, true), signer.writePublicKey(t, armoredKey)})
+			require.NoError(t, err)
+
+			signature := base64.StdEncoding.EncodeToString(signer.sign(t, bundle, armoredSignature))
+			assert.NoError(t, v.verify(bundle, signature), "armored key %t, armored signature %t", armoredKey, armoredSignature)
+			assert.Error(t, v.verify([]byte("tampered"), signature), "armored key %t, armored signature %t", armoredKey, armoredSignature)
+		}
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		v, err := newSignatureVerifier([]string{otherSigner.writePublicKey(t, false)})
+		require.NoError(t, err)
+
+		err = v.verify(bundle, base64.StdEncoding.EncodeToString(signer.sign(t, bundle, false)))
+		require.Error(t, err)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		v, err := newSignatureVerifier([]string{signer.writePublicKey(t, false)})
+		require.NoError(t, err)
+
+		require.EqualError(t, v.verify(bundle, ""), "bundle is not signed")
+	})
+}
+
+func TestAddVerifiesSignature(t *testing.T) {
+	_, bundles := setupFakes(t)
+	signer := newTestSigner(t)
+	publicKey := signer.writePublicKey(t, true)
+
+	bundleURL := fakePluginHost + "/mattermost-plugin-demo-v0.2.0.tar.gz"
+	bundles.serve(bundleURL, "mattermost-plugin-demo-v0.2.0.tar.gz")
+
+	t.Run("mismatched signature", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+		err := runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--official", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--public-key", publicKey)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to verify signature")
+	})
+
+	t.Run("matching signature", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+		signature, err := base64.StdEncoding.DecodeString(signer.signTestdata(t, "mattermost-plugin-demo-v0.2.0.tar.gz"))
+		require.NoError(t, err)
+		bundles.serveData(bundleURL+".sig", signature)
+
+		err = runGenerator(t, "add", "mattermost-plugin-demo", "v0.2.0", "--official", "--database", dbFile, "--remote-plugin-store", fakePluginHost, "--public-key", publicKey)
+		require.NoError(t, err)
+	})
+
+	t.Run("local bundle", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, nil))
+
+		bundlePath := filepath.Join("testdata", "mattermost-plugin-demo-v0.1.0.tar.gz")
+		downloadURL := "https://private.example.com/demo-0.1.0.tar.gz"
+
+		err := runGenerator(t, "add", "--bundle", bundlePath, "--download-url", downloadURL, "--community", "--database", dbFile, "--public-key", publicKey)
+		require.Error(t, err)
+
+		signaturePath := filepath.Join(t.TempDir(), "demo.tar.gz.sig")
+		signature, err := base64.StdEncoding.DecodeString(signer.signTestdata(t, "mattermost-plugin-demo-v0.1.0.tar.gz"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(signaturePath, signature, 0600))
+
+		err = runGenerator(t, "add", "--bundle", bundlePath, "--signature", signaturePath, "--download-url", downloadURL, "--community", "--database", dbFile, "--public-key", publicKey)
+		require.NoError(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	_, bundles := setupFakes(t)
+	signer := newTestSigner(t)
+	publicKey := signer.writePublicKey(t, false)
+
+	signed := makePlugin("com.mattermost.demo-plugin", "0.2.0")
+	signed.DownloadURL = fakePluginHost + "/mattermost-plugin-demo-v0.2.0.tar.gz"
+	signed.Signature = signer.signTestdata(t, "mattermost-plugin-demo-v0.2.0.tar.gz")
+	signed.Platforms.LinuxAmd64 = model.PlatformBundleMetadata{
+		DownloadURL: fakePluginHost + "/mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz",
+		Signature:   signer.signTestdata(t, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz"),
+	}
+	bundles.serve(signed.DownloadURL, "mattermost-plugin-demo-v0.2.0.tar.gz")
+	bundles.serve(signed.Platforms.LinuxAmd64.DownloadURL, "mattermost-plugin-demo-v0.2.0-linux-amd64.tar.gz")
+
+	unsigned := makePlugin("com.mattermost.demo-plugin", "0.0.1")
+	unsigned.Signature = ""
+
+	t.Run("public key is required", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{signed}))
+
+		err := runGenerator(t, "verify", "--database", dbFile)
+		require.EqualError(t, err, "at least one --public-key is required")
+	})
+
+	t.Run("valid signatures", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{signed, unsigned}))
+
+		err := runGenerator(t, "verify", "--database", dbFile, "--public-key", publicKey)
+		require.NoError(t, err)
+		assert.Contains(t, bundles.requests, "GET "+signed.Platforms.LinuxAmd64.DownloadURL)
+	})
+
+	t.Run("mismatched signature", func(t *testing.T) {
+		mismatched := makePlugin("com.mattermost.demo-plugin", "0.1.0")
+		mismatched.DownloadURL = fakePluginHost + "/mattermost-plugin-demo-v0.1.0.tar.gz"
+		mismatched.Signature = signed.Signature
+		bundles.serve(mismatched.DownloadURL, "mattermost-plugin-demo-v0.1.0.tar.gz")
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, []*model.Plugin{signed, mismatched}))
+
+		err := runGenerator(t, "verify", "--database", dbFile, "--public-key", publicKey)
+		require.EqualError(t, err, "failed to verify 1 of 3 bundles in "+dbFile)
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v28/github"
+	"golang.org/x/oauth2"
+)
+
+// releaseSource is the subset of the GitHub repositories API used to discover plugin releases.
+// *github.RepositoriesService satisfies it.
+type releaseSource interface {
+	Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	ListReleases(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error)
+}
+
+// bundleFetcher performs the HTTP requests fetching plugin bundles and signatures. *http.Client
+// satisfies it.
+type bundleFetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+var (
+	// newReleaseSource creates the source of plugin releases, authenticating with the given GitHub
+	// token if not empty. Tests replace it with a fake.
+	newReleaseSource = func(ctx context.Context, githubToken string) releaseSource {
+		if githubToken == "" {
+			return github.NewClient(nil).Repositories
+		}
+
+		ts := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: githubToken},
+		)
+
+		return github.NewClient(oauth2.NewClient(ctx, ts)).Repositories
+	}
+
+	// fetcher fetches plugin bundles and signatures. Tests replace it with a fake.
+	fetcher bundleFetcher = http.DefaultClient
+)
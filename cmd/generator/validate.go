@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/output"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+
+	svgDataURIPrefix = "data:image/svg+xml;base64,"
+)
+
+func init() {
+	generatorCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().String("format", output.Table, "The output format, either table or json.")
+	validateCmd.Flags().Bool("strict", false, "Fail on warnings as well as errors.")
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check every entry in the plugins.json database.",
+	Long: "The validate command checks each entry in the database for a valid version and manifest, a unique id and version " +
+		"and download URL, a signature, well-formed URLs, an SVG icon, known metadata and a complete set of platform bundles declared by the manifest. " +
+		"It also checks that the author type and hosting of a plugin are consistent across its versions.\n\n" +
+		"Findings are reported as errors or warnings, e.g. for historical entries without a signature. The command fails " +
+		"if any errors are found, or with --strict if any findings are.",
+	Example: "generator validate --format json",
+	Args:    cobra.NoArgs,
+	RunE: func(command *cobra.Command, _ []string) error {
+		command.SilenceUsage = true
+
+		format, _ := command.Flags().GetString("format")
+		if format != output.Table && format != output.JSON {
+			return errors.Errorf("unsupported format %s, expected %s or %s", format, output.Table, output.JSON)
+		}
+
+		strict, _ := command.Flags().GetBool("strict")
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		findings := validateDatabase(plugins)
+
+		if format == output.JSON {
+			encoder := json.NewEncoder(command.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			if err = encoder.Encode(findings); err != nil {
+				return errors.Wrap(err, "failed to write findings")
+			}
+		} else if len(findings) > 0 {
+			w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SEVERITY\tID\tVERSION\tCHECK\tMESSAGE")
+			for _, finding := range findings {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", finding.Severity, output.OrDash(finding.ID), output.OrDash(finding.Version), finding.Check, finding.Message)
+			}
+			if err = w.Flush(); err != nil {
+				return errors.Wrap(err, "failed to write findings")
+			}
+		}
+
+		var errorCount, warningCount int
+		for _, finding := range findings {
+			if finding.Severity == severityError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+
+		if errorCount > 0 || (strict && warningCount > 0) {
+			return errors.Errorf("found %d errors and %d warnings in %s", errorCount, warningCount, dbFile)
+		}
+
+		logger.Infof("Validated %d plugins in %s with %d warnings", len(plugins), dbFile, warningCount)
+
+		return nil
+	},
+}
+
+// validationFinding is a problem found with an entry of the plugins database, or with a plugin
+// across its entries.
+type validationFinding struct {
+	Severity string `json:"severity"`
+	Index    int    `json:"index"` // The position of the entry in the database, or -1 for findings across entries
+	ID       string `json:"id"`
+	Version  string `json:"version,omitempty"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+// validateDatabase checks every entry in the given plugins database, returning the findings
+// ordered by entry.
+func validateDatabase(plugins []*model.Plugin) []validationFinding {
+	findings := []validationFinding{}
+
+	releases := make(map[string]int, len(plugins))
+	downloadURLs := make(map[string]int, len(plugins))
+	authorTypes := make(map[string][]string)
+	hostings := make(map[string][]string)
+
+	for i, plugin := range plugins {
+		report := func(severity, check, format string, args ...any) {
+			finding := validationFinding{
+				Severity: severity,
+				Index:    i,
+				Check:    check,
+				Message:  fmt.Sprintf(format, args...),
+			}
+			if plugin.Manifest != nil {
+				finding.ID = plugin.Manifest.Id
+				finding.Version = plugin.Manifest.Version
+			}
+			findings = append(findings, finding)
+		}
+
+		if plugin.Manifest == nil {
+			report(severityError, "manifest", "missing manifest")
+			continue
+		}
+		id := plugin.Manifest.Id
+
+		if _, err := semver.Parse(plugin.Manifest.Version); err != nil {
+			report(severityError, "version", "invalid version %q: %s", plugin.Manifest.Version, err)
+		}
+
+		if err := plugin.Manifest.IsValid(); err != nil {
+			report(severityWarning, "manifest", "invalid manifest: %s", err)
+		}
+
+		release := id + "@" + plugin.Manifest.Version
+		if previous, ok := releases[release]; ok {
+			report(severityError, "duplicate", "same id and version as entry %d", previous)
+		} else {
+			releases[release] = i
+		}
+
+		if previous, ok := downloadURLs[plugin.DownloadURL]; ok && plugin.DownloadURL != "" {
+			report(severityError, "duplicate", "same download url as entry %d", previous)
+		} else {
+			downloadURLs[plugin.DownloadURL] = i
+		}
+
+		if err := validateURL(plugin.DownloadURL); err != nil {
+			report(severityError, "url", "invalid download url %q: %s", plugin.DownloadURL, err)
+		}
+		if plugin.HomepageURL != "" {
+			if err := validateURL(plugin.HomepageURL); err != nil {
+				report(severityError, "url", "invalid homepage url %q: %s", plugin.HomepageURL, err)
+			}
+		}
+		if plugin.ReleaseNotesURL != "" {
+			if err := validateURL(plugin.ReleaseNotesURL); err != nil {
+				report(severityError, "url", "invalid release notes url %q: %s", plugin.ReleaseNotesURL, err)
+			}
+		}
+
+		if plugin.Signature == "" {
+			report(severityWarning, "signature", "missing signature")
+		} else if _, err := base64.StdEncoding.DecodeString(plugin.Signature); err != nil {
+			report(severityError, "signature", "signature is not base64 encoded: %s", err)
+		}
+
+		if plugin.IconData != "" {
+			if err := validateIconData(plugin.IconData); err != nil {
+				report(severityError, "icon", "invalid icon data: %s", err)
+			}
+		}
+
+		if err := validateMetadata(plugin); err != nil {
+			report(severityError, "metadata", "%s", err)
+		}
+
+		validatePlatformBundles(plugin, report)
+
+		authorTypes[id] = appendUnique(authorTypes[id], string(plugin.AuthorType))
+		hostings[id] = appendUnique(hostings[id], output.OrDash(string(plugin.Hosting)))
+	}
+
+	ids := make([]string, 0, len(authorTypes))
+	for id := range authorTypes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if len(authorTypes[id]) > 1 {
+			findings = append(findings, validationFinding{
+				Severity: severityWarning,
+				Index:    -1,
+				ID:       id,
+				Check:    "consistency",
+				Message:  fmt.Sprintf("author type differs across versions: %s", strings.Join(authorTypes[id], ", ")),
+			})
+		}
+
+		if len(hostings[id]) > 1 {
+			findings = append(findings, validationFinding{
+				Severity: severityWarning,
+				Index:    -1,
+				ID:       id,
+				Check:    "consistency",
+				Message:  fmt.Sprintf("hosting differs across versions: %s", strings.Join(hostings[id], ", ")),
+			})
+		}
+	}
+
+	return findings
+}
+
+// validateURL checks that the given URL is an absolute http(s) URL.
+func validateURL(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("missing url")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return errors.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+
+	return nil
+}
+
+// validateIconData checks that the given icon data is a base64 encoded SVG data URI, as written by
+// getIconDataFromTarFile.
+func validateIconData(iconData string) error {
+	encoded, ok := strings.CutPrefix(iconData, svgDataURIPrefix)
+	if !ok {
+		return errors.Errorf("expected prefix %s", svgDataURIPrefix)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode base64")
+	}
+
+	if !strings.Contains(string(data), "<svg") {
+		return errors.New("not an SVG image")
+	}
+
+	return nil
+}
+
+// validateMetadata checks that the author type, hosting and release stage of the given plugin are
+// known values.
+func validateMetadata(plugin *model.Plugin) error {
+	if !slices.Contains([]model.AuthorType{model.Mattermost, model.Partner, model.Community}, plugin.AuthorType) {
+		return errors.Errorf("unknown author type %q", plugin.AuthorType)
+	}
+
+	if !slices.Contains([]model.HostingType{"", model.Cloud, model.OnPrem}, plugin.Hosting) {
+		return errors.Errorf("unknown hosting %q", plugin.Hosting)
+	}
+
+	if !slices.Contains([]model.ReleaseStage{model.Production, model.Beta, model.Experimental}, plugin.ReleaseStage) {
+		return errors.Errorf("unknown release stage %q", plugin.ReleaseStage)
+	}
+
+	return nil
+}
+
+// validatePlatformBundles checks that each platform bundle of the given plugin has a well-formed,
+// distinct download URL and a signature, that its platform is declared by the server executables of
+// the manifest, and that bundles are provided for all platforms or none.
+func validatePlatformBundles(plugin *model.Plugin, report func(severity, check, format string, args ...any)) {
+	bundles := []struct {
+		platform string
+		bundle   model.PlatformBundleMetadata
+	}{
+		{model.LinuxAmd64, plugin.Platforms.LinuxAmd64},
+		{model.DarwinAmd64, plugin.Platforms.DarwinAmd64},
+		{model.WindowsAmd64, plugin.Platforms.WindowsAmd64},
+	}
+
+	var present, missing []string
+	declared := declaredPlatforms(plugin.Manifest)
+	downloadURLs := map[string]bool{plugin.DownloadURL: true}
+	for _, b := range bundles {
+		if b.bundle.DownloadURL == "" {
+			if b.bundle.Signature != "" {
+				report(severityError, "platforms", "%s bundle has a signature but no download url", b.platform)
+			}
+			missing = append(missing, b.platform)
+			continue
+		}
+		present = append(present, b.platform)
+
+		if !slices.Contains(declared, b.platform) {
+			report(severityWarning, "platforms", "%s bundle is not declared by the server executables of the manifest", b.platform)
+		}
+
+		if err := validateURL(b.bundle.DownloadURL); err != nil {
+			report(severityError, "platforms", "invalid %s download url %q: %s", b.platform, b.bundle.DownloadURL, err)
+		}
+
+		if downloadURLs[b.bundle.DownloadURL] {
+			report(severityError, "platforms", "%s download url %s is not distinct", b.platform, b.bundle.DownloadURL)
+		}
+		downloadURLs[b.bundle.DownloadURL] = true
+
+		if b.bundle.Signature == "" {
+			report(severityError, "platforms", "%s bundle is missing a signature", b.platform)
+		} else if _, err := base64.StdEncoding.DecodeString(b.bundle.Signature); err != nil {
+			report(severityError, "platforms", "%s signature is not base64 encoded: %s", b.platform, err)
+		}
+	}
+
+	if len(present) > 0 && len(missing) > 0 {
+		report(severityWarning, "platforms", "missing bundles for %s, which fall back to the default bundle", strings.Join(missing, ", "))
+	}
+}
+
+// appendUnique appends the given value unless already present.
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// makeValidPlugin returns a plugin passing all checks of the validate command.
+func makeValidPlugin(id, version string) *model.Plugin {
+	plugin := makePlugin(id, version)
+	plugin.Signature = base64.StdEncoding.EncodeToString([]byte("signature"))
+	plugin.IconData = svgDataURIPrefix + base64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+	plugin.AuthorType = model.Mattermost
+	plugin.ReleaseStage = model.Production
+	plugin.Manifest.Server = &mattermostModel.ManifestServer{Executables: map[string]string{
+		model.LinuxAmd64:   "server/dist/plugin-linux-amd64",
+		model.DarwinAmd64:  "server/dist/plugin-darwin-amd64",
+		model.WindowsAmd64: "server/dist/plugin-windows-amd64.exe",
+	}}
+
+	return plugin
+}
+
+func TestValidateDatabase(t *testing.T) {
+	t.Run("valid plugins", func(t *testing.T) {
+		withPlatforms := makeValidPlugin("com.example.a", "1.1.0")
+		withPlatforms.Platforms = model.PlatformBundles{
+			LinuxAmd64:   model.PlatformBundleMetadata{DownloadURL: "https://example.com/a-1.1.0-linux-amd64.tar.gz", Signature: withPlatforms.Signature},
+			DarwinAmd64:  model.PlatformBundleMetadata{DownloadURL: "https://example.com/a-1.1.0-osx-amd64.tar.gz", Signature: withPlatforms.Signature},
+			WindowsAmd64: model.PlatformBundleMetadata{DownloadURL: "https://example.com/a-1.1.0-windows-amd64.tar.gz", Signature: withPlatforms.Signature},
+		}
+
+		findings := validateDatabase([]*model.Plugin{makeValidPlugin("com.example.a", "1.0.0"), withPlatforms, makeValidPlugin("com.example.b", "1.0.0")})
+		assert.Empty(t, findings)
+	})
+
+	testCases := []struct {
+		description string
+		modify      func(plugin *model.Plugin)
+		expected    validationFinding
+	}{
+		{
+			"invalid version",
+			func(plugin *model.Plugin) { plugin.Manifest.Version = "v1.0" },
+			validationFinding{Severity: severityError, Check: "version"},
+		},
+		{
+			"missing signature",
+			func(plugin *model.Plugin) { plugin.Signature = "" },
+			validationFinding{Severity: severityWarning, Check: "signature", Message: "missing signature"},
+		},
+		{
+			"signature not base64 encoded",
+			func(plugin *model.Plugin) { plugin.Signature = "not base64" },
+			validationFinding{Severity: severityError, Check: "signature"},
+		},
+		{
+			"relative download url",
+			func(plugin *model.Plugin) { plugin.DownloadURL = "plugin.tar.gz" },
+			validationFinding{Severity: severityError, Check: "url", Message: `invalid download url "plugin.tar.gz": unsupported scheme ""`},
+		},
+		{
+			"invalid homepage url",
+			func(plugin *model.Plugin) { plugin.HomepageURL = "ftp://example.com" },
+			validationFinding{Severity: severityError, Check: "url", Message: `invalid homepage url "ftp://example.com": unsupported scheme "ftp"`},
+		},
+		{
+			"icon is not a data uri",
+			func(plugin *model.Plugin) { plugin.IconData = "icon.svg" },
+			validationFinding{Severity: severityError, Check: "icon", Message: "invalid icon data: expected prefix " + svgDataURIPrefix},
+		},
+		{
+			"icon is not an svg",
+			func(plugin *model.Plugin) {
+				plugin.IconData = svgDataURIPrefix + base64.StdEncoding.EncodeToString([]byte("\x89PNG"))
+			},
+			validationFinding{Severity: severityError, Check: "icon", Message: "invalid icon data: not an SVG image"},
+		},
+		{
+			"unknown author type",
+			func(plugin *model.Plugin) { plugin.AuthorType = "" },
+			validationFinding{Severity: severityError, Check: "metadata", Message: `unknown author type ""`},
+		},
+		{
+			"platform bundle without signature",
+			func(plugin *model.Plugin) {
+				plugin.Platforms.LinuxAmd64.DownloadURL = "https://example.com/plugin-linux-amd64.tar.gz"
+			},
+			validationFinding{Severity: severityError, Check: "platforms", Message: "linux-amd64 bundle is missing a signature"},
+		},
+		{
+			"platform bundle reusing the download url",
+			func(plugin *model.Plugin) {
+				plugin.Platforms.LinuxAmd64.DownloadURL = plugin.DownloadURL
+				plugin.Platforms.LinuxAmd64.Signature = plugin.Signature
+			},
+			validationFinding{Severity: severityError, Check: "platforms", Message: "linux-amd64 download url " + makePlugin("com.example.a", "1.0.0").DownloadURL + " is not distinct"},
+		},
+		{
+			"platform bundle not declared by the manifest",
+			func(plugin *model.Plugin) {
+				delete(plugin.Manifest.Server.Executables, model.DarwinAmd64)
+				plugin.Platforms.DarwinAmd64.DownloadURL = "https://example.com/plugin-osx-amd64.tar.gz"
+				plugin.Platforms.DarwinAmd64.Signature = plugin.Signature
+			},
+			validationFinding{Severity: severityWarning, Check: "platforms", Message: "darwin-amd64 bundle is not declared by the server executables of the manifest"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			plugin := makeValidPlugin("com.example.a", "1.0.0")
+			tc.modify(plugin)
+
+			findings := validateDatabase([]*model.Plugin{makeValidPlugin("com.example.b", "1.0.0"), plugin})
+			require.NotEmpty(t, findings)
+
+			finding := findings[0]
+			assert.Equal(t, tc.expected.Severity, finding.Severity)
+			assert.Equal(t, tc.expected.Check, finding.Check)
+			assert.Equal(t, 1, finding.Index)
+			assert.Equal(t, "com.example.a", finding.ID)
+			if tc.expected.Message != "" {
+				assert.Equal(t, tc.expected.Message, finding.Message)
+			}
+		})
+	}
+
+	t.Run("duplicates", func(t *testing.T) {
+		duplicateURL := makeValidPlugin("com.example.b", "1.0.0")
+		duplicateURL.DownloadURL = makePlugin("com.example.a", "1.0.0").DownloadURL
+
+		findings := validateDatabase([]*model.Plugin{makeValidPlugin("com.example.a", "1.0.0"), makeValidPlugin("com.example.a", "1.0.0"), duplicateURL})
+		assert.Equal(t, []validationFinding{
+			{Severity: severityError, Index: 1, ID: "com.example.a", Version: "1.0.0", Check: "duplicate", Message: "same id and version as entry 0"},
+			{Severity: severityError, Index: 1, ID: "com.example.a", Version: "1.0.0", Check: "duplicate", Message: "same download url as entry 0"},
+			{Severity: severityError, Index: 2, ID: "com.example.b", Version: "1.0.0", Check: "duplicate", Message: "same download url as entry 0"},
+		}, findings)
+	})
+
+	t.Run("inconsistent author type and hosting", func(t *testing.T) {
+		older := makeValidPlugin("com.example.a", "1.0.0")
+		older.AuthorType = model.Community
+		older.Hosting = model.Cloud
+
+		findings := validateDatabase([]*model.Plugin{makeValidPlugin("com.example.a", "1.1.0"), older})
+		assert.Equal(t, []validationFinding{
+			{Severity: severityWarning, Index: -1, ID: "com.example.a", Check: "consistency", Message: "author type differs across versions: mattermost, community"},
+			{Severity: severityWarning, Index: -1, ID: "com.example.a", Check: "consistency", Message: "hosting differs across versions: -, cloud"},
+		}, findings)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	// runValidate runs the validate command on a database of the given plugins, returning the
+	// findings written as json.
+	runValidate := func(t *testing.T, plugins []*model.Plugin, args ...string) ([]validationFinding, error) {
+		t.Helper()
+
+		dbFile := filepath.Join(t.TempDir(), "plugins.json")
+		require.NoError(t, pluginsToDatabase(dbFile, plugins))
+
+		output := &bytes.Buffer{}
+		generatorCmd.SetOut(output)
+		t.Cleanup(func() {
+			generatorCmd.SetOut(nil)
+		})
+
+		err := runGenerator(t, append([]string{"validate", "--format", "json", "--database", dbFile}, args...)...)
+
+		var findings []validationFinding
+		require.NoError(t, json.Unmarshal(output.Bytes(), &findings))
+
+		return findings, err
+	}
+
+	t.Run("valid database", func(t *testing.T) {
+		findings, err := runValidate(t, []*model.Plugin{makeValidPlugin("com.example.a", "1.0.0")})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("warnings only fail with strict", func(t *testing.T) {
+		plugin := makeValidPlugin("com.example.a", "1.0.0")
+		plugin.Signature = ""
+
+		findings, err := runValidate(t, []*model.Plugin{plugin})
+		require.NoError(t, err)
+		assert.Len(t, findings, 1)
+
+		findings, err = runValidate(t, []*model.Plugin{plugin}, "--strict")
+		require.Error(t, err)
+		assert.Len(t, findings, 1)
+	})
+
+	t.Run("errors fail", func(t *testing.T) {
+		plugin := makeValidPlugin("com.example.a", "1.0.0")
+		plugin.IconData = "icon.svg"
+
+		findings, err := runValidate(t, []*model.Plugin{plugin})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "found 1 errors and 0 warnings")
+		require.Len(t, findings, 1)
+		assert.Equal(t, "icon", findings[0].Check)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		err := runGenerator(t, "validate", "--format", "yaml")
+		require.EqualError(t, err, "unsupported format yaml, expected table or json")
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+func init() {
+	generatorCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().Int("concurrency", 4, "How many bundles to download and verify at once.")
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the signatures of all bundles in the plugins.json database.",
+	Long: "The verify command downloads every bundle in the database, including platform-specific bundles, " +
+		"and checks its signature against the public keys given with --public-key. Entries without a signature " +
+		"are skipped with a warning.",
+	Example: "generator verify --public-key mattermost.asc",
+	Args:    cobra.NoArgs,
+	RunE: func(command *cobra.Command, _ []string) error {
+		command.SilenceUsage = true
+
+		if verifier == nil {
+			return errors.New("at least one --public-key is required")
+		}
+
+		concurrency, _ := command.Flags().GetInt("concurrency")
+		if concurrency < 1 {
+			return errors.New("concurrency must be at least 1")
+		}
+
+		dbFile, err := command.Flags().GetString("database")
+		if err != nil {
+			return err
+		}
+
+		plugins, err := pluginsFromDatabase(dbFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read plugins from database")
+		}
+
+		bundles := signedBundles(plugins)
+		failures := make([]error, len(bundles))
+
+		g := errgroup.Group{}
+		g.SetLimit(concurrency)
+		for i, bundle := range bundles {
+			g.Go(func() error {
+				logger := logger.WithFields(logrus.Fields{
+					"id":      bundle.plugin.Manifest.Id,
+					"version": bundle.plugin.Manifest.Version,
+					"url":     bundle.url,
+				})
+
+				if bundle.signature == "" {
+					logger.Warn("Skipping unsigned bundle")
+					return nil
+				}
+
+				if failures[i] = verifier.verifyRemote(bundle.url, bundle.signature); failures[i] != nil {
+					logger.WithError(failures[i]).Error("Failed to verify bundle")
+				} else {
+					logger.Debug("Verified bundle")
+				}
+
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		failed, unsigned := 0, 0
+		for i, failure := range failures {
+			switch {
+			case failure != nil:
+				failed++
+			case bundles[i].signature == "":
+				unsigned++
+			}
+		}
+
+		if failed > 0 {
+			return errors.Errorf("failed to verify %d of %d bundles in %s", failed, len(bundles), dbFile)
+		}
+
+		logger.Infof("Verified %d bundles in %s, skipping %d unsigned bundles", len(bundles)-unsigned, dbFile, unsigned)
+
+		return nil
+	},
+}
+
+// signedBundle is a bundle of a plugin, either its default bundle or a platform-specific one.
+type signedBundle struct {
+	plugin    *model.Plugin
+	url       string
+	signature string
+}
+
+// signedBundles lists the default and platform-specific bundles of the given plugins.
+func signedBundles(plugins []*model.Plugin) []signedBundle {
+	var bundles []signedBundle
+	for _, plugin := range plugins {
+		bundles = append(bundles, signedBundle{plugin, plugin.DownloadURL, plugin.Signature})
+
+		for _, platformBundle := range []model.PlatformBundleMetadata{plugin.Platforms.LinuxAmd64, plugin.Platforms.DarwinAmd64, plugin.Platforms.WindowsAmd64} {
+			if platformBundle.DownloadURL != "" {
+				bundles = append(bundles, signedBundle{plugin, platformBundle.DownloadURL, platformBundle.Signature})
+			}
+		}
+	}
+
+	return bundles
+}
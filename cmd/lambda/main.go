@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	_ "embed"
+	"sync"
+	"time"
 
 	"github.com/akrylysov/algnhsa"
 	"github.com/gorilla/mux"
@@ -10,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/mattermost/mattermost-marketplace/internal/api"
+	"github.com/mattermost/mattermost-marketplace/internal/model"
 	"github.com/mattermost/mattermost-marketplace/internal/store"
 )
 
@@ -17,10 +21,23 @@ var (
 	// upstreamURL may be compiled into the binary by defining $BUILD_UPSTREAM_URL
 	upstreamURL = ""
 
-	//go:embed plugins.json
+	// databaseURL may be compiled into the binary by defining $BUILD_DATABASE_URL, fetching the
+	// plugins database remotely instead of using the embedded copy.
+	databaseURL = ""
+
+	// databaseName may be compiled into the binary by defining $BUILD_DATABASE_NAME, naming the
+	// plugins served from the database when merged with an upstream marketplace. Defaults to local.
+	databaseName = ""
+
+	// database is the compressed, pre-validated catalog built by `generator catalog`.
+	//
+	//go:embed plugins.json.gz
 	database []byte
 )
 
+// databasePollInterval is how often a remote database is checked for changes.
+const databasePollInterval = time.Minute
+
 var logger *logrus.Logger
 
 func main() {
@@ -31,7 +48,14 @@ func main() {
 }
 
 func newStaticStore(logger logrus.FieldLogger) (*store.StaticStore, error) {
-	staticStore, err := store.NewStaticFromReader(bytes.NewReader(database), logger)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(database))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress database")
+	}
+	defer gzipReader.Close()
+
+	// The catalog was validated when it was built, so skip doing so again on every cold start.
+	staticStore, err := store.NewStaticFromValidatedReader(gzipReader, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to initialize store")
 	}
@@ -39,14 +63,45 @@ func newStaticStore(logger logrus.FieldLogger) (*store.StaticStore, error) {
 	return staticStore, nil
 }
 
+// lazyStore defers decoding the embedded database until it is first queried, keeping it off the
+// cold start path of requests that don't need it, e.g. health checks.
+type lazyStore struct {
+	logger logrus.FieldLogger
+
+	once        sync.Once
+	staticStore *store.StaticStore
+	err         error
+}
+
+// GetPlugins decodes the embedded database if necessary, then fetches the given page of plugins.
+func (s *lazyStore) GetPlugins(pluginFilter *model.PluginFilter) ([]*model.Plugin, error) {
+	s.once.Do(func() {
+		s.staticStore, s.err = newStaticStore(s.logger)
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return s.staticStore.GetPlugins(pluginFilter)
+}
+
 func listenAndServe() error {
 	logger = logrus.New()
 
 	var apiStore store.Store
 	var err error
-	apiStore, err = newStaticStore(logger)
-	if err != nil {
-		return err
+	if databaseURL != "" {
+		var remoteStore *store.Remote
+		remoteStore, err = store.NewRemote(databaseURL, logger)
+		if err != nil {
+			return errors.Wrap(err, "failed to initialize remote store")
+		}
+		// Background goroutines are frozen between invocations, so refresh when serving requests.
+		remoteStore.SetRefreshInterval(databasePollInterval)
+
+		apiStore = remoteStore
+	} else {
+		apiStore = &lazyStore{logger: logger}
 	}
 
 	if upstreamURL != "" {
@@ -55,7 +110,12 @@ func listenAndServe() error {
 			return errors.Wrap(err, "failed to initialize upstream store")
 		}
 
-		apiStore = store.NewMerged(logger, apiStore, upstreamStore)
+		name := databaseName
+		if name == "" {
+			name = "local"
+		}
+
+		apiStore = store.NewMerged(logger, store.Named(apiStore, name), upstreamStore)
 	}
 
 	router := mux.NewRouter()
@@ -1,12 +1,63 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io"
 	"testing"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/store"
 )
 
 func TestNewStaticStore(t *testing.T) {
 	_, err := newStaticStore(logger)
 	require.NoError(t, err)
 }
+
+func TestLazyStore(t *testing.T) {
+	lazy := &lazyStore{logger: logger}
+
+	plugins, err := lazy.GetPlugins(&model.PluginFilter{PerPage: model.AllPerPage})
+	require.NoError(t, err)
+	require.NotEmpty(t, plugins)
+}
+
+// BenchmarkNewStaticStore compares decoding the compressed, pre-validated catalog against decoding
+// it uncompressed, with and without validation, the latter being what was done previously.
+func BenchmarkNewStaticStore(b *testing.B) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(database))
+	require.NoError(b, err)
+	rawDatabase, err := io.ReadAll(gzipReader)
+	require.NoError(b, err)
+
+	b.Run("compressed, pre-validated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := newStaticStore(logger)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("uncompressed, pre-validated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := store.NewStaticFromValidatedReader(bytes.NewReader(rawDatabase), logger)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("uncompressed, validated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := store.NewStaticFromReader(bytes.NewReader(rawDatabase), logger)
+			require.NoError(b, err)
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-marketplace/internal/api"
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/output"
+)
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.AddCommand(queryListCmd)
+	queryCmd.AddCommand(querySearchCmd)
+	queryCmd.AddCommand(queryShowCmd)
+	queryCmd.AddCommand(queryCompareCmd)
+
+	queryCmd.PersistentFlags().String("url", "https://api.integrations.mattermost.com", "The marketplace to query.")
+	queryCmd.PersistentFlags().String("format", output.Table, "The output format, either table or json.")
+	queryCmd.PersistentFlags().Duration("timeout", 30*time.Second, "How long to wait for each request.")
+	queryCmd.PersistentFlags().String("server-version", "", "Only return plugins compatible with this server version.")
+	queryCmd.PersistentFlags().Bool("enterprise-plugins", false, "Whether the server is licensed for enterprise plugins.")
+	queryCmd.PersistentFlags().Bool("cloud", false, "Whether the server is hosted in Mattermost Cloud.")
+	queryCmd.PersistentFlags().String("platform", "", "The platform of the server, e.g. linux-amd64.")
+	queryCmd.PersistentFlags().Bool("return-all-versions", false, "Return every compatible version rather than only the latest.")
+	queryCmd.PersistentFlags().Int("page", 0, "Fetch only this page rather than walking every page.")
+	queryCmd.PersistentFlags().Int("per-page", 100, "The number of plugins to fetch per page.")
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query a running marketplace.",
+	Long: "The query commands show the plugins a marketplace offers to a given server, e.g. a server " +
+		"at version 9.11.0 in Mattermost Cloud, without having to craft API requests by hand.",
+}
+
+var queryListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the plugins offered to a server.",
+	Example: "marketplace query list --server-version 9.11.0 --cloud",
+	Args:    cobra.NoArgs,
+	RunE: func(command *cobra.Command, _ []string) error {
+		command.SilenceUsage = true
+
+		return queryPlugins(command, "")
+	},
+}
+
+var querySearchCmd = &cobra.Command{
+	Use:     "search <term>",
+	Short:   "Search for plugins by id, name or description.",
+	Example: "marketplace query search jira --server-version 9.11.0",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		return queryPlugins(command, args[0])
+	},
+}
+
+var queryShowCmd = &cobra.Command{
+	Use:     "show <plugin id>",
+	Short:   "Show the details of the latest version of a plugin offered to a server.",
+	Example: "marketplace query show com.mattermost.plugin-jira --server-version 9.11.0 --cloud",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		client, request, err := queryClientAndRequest(command)
+		if err != nil {
+			return err
+		}
+
+		var plugins []*model.Plugin
+		if request.ReturnAllVersions {
+			request.PluginID = args[0]
+			request.PerPage = model.AllPerPage
+			plugins, err = client.GetPlugins(context.Background(), request)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get plugin %s", args[0])
+			}
+		} else {
+			var plugin *model.Plugin
+			plugin, err = client.GetPlugin(context.Background(), args[0], request)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get plugin %s", args[0])
+			}
+			if plugin != nil {
+				plugins = append(plugins, plugin)
+			}
+		}
+
+		if len(plugins) == 0 {
+			return errors.Errorf("plugin %s is not offered to the given server", args[0])
+		}
+
+		format, _ := command.Flags().GetString("format")
+		if format == output.JSON {
+			return writeJSON(command.OutOrStdout(), plugins)
+		}
+
+		w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+		for i, plugin := range plugins {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			writePluginDetails(w, plugin)
+		}
+
+		return w.Flush()
+	},
+}
+
+var queryCompareCmd = &cobra.Command{
+	Use:   "compare <other url>",
+	Short: "Compare the plugins offered to a server by two marketplaces.",
+	Long: "The compare command lists the plugins whose versions differ between the marketplace given by " +
+		"--url and the other marketplace, given the same server parameters.",
+	Example: "marketplace query compare http://localhost:8085 --server-version 9.11.0",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		client, request, err := queryClientAndRequest(command)
+		if err != nil {
+			return err
+		}
+		timeout, _ := command.Flags().GetDuration("timeout")
+		otherClient := api.NewClient(args[0], api.WithTimeout(timeout))
+
+		plugins, err := fetchAllPlugins(client, request)
+		if err != nil {
+			return errors.Wrapf(err, "failed to query %s", client.Address)
+		}
+
+		otherPlugins, err := fetchAllPlugins(otherClient, request)
+		if err != nil {
+			return errors.Wrapf(err, "failed to query %s", otherClient.Address)
+		}
+
+		differences := comparePlugins(plugins, otherPlugins)
+
+		format, _ := command.Flags().GetString("format")
+		if format == output.JSON {
+			return writeJSON(command.OutOrStdout(), differences)
+		}
+
+		w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\t%s\t%s\tSTATUS\n", client.Address, otherClient.Address)
+		for _, difference := range differences {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", difference.ID, output.OrDash(difference.Versions), output.OrDash(difference.OtherVersions), difference.Status)
+		}
+
+		return w.Flush()
+	},
+}
+
+// queryClientAndRequest builds the client and plugins request described by the query flags.
+func queryClientAndRequest(command *cobra.Command) (*api.Client, *api.GetPluginsRequest, error) {
+	flags := command.Flags()
+
+	format, _ := flags.GetString("format")
+	if format != output.Table && format != output.JSON {
+		return nil, nil, errors.Errorf("unsupported format %s, expected %s or %s", format, output.Table, output.JSON)
+	}
+
+	marketplaceURL, _ := flags.GetString("url")
+	timeout, _ := flags.GetDuration("timeout")
+	client := api.NewClient(marketplaceURL, api.WithTimeout(timeout))
+
+	request := &api.GetPluginsRequest{}
+	request.ServerVersion, _ = flags.GetString("server-version")
+	request.EnterprisePlugins, _ = flags.GetBool("enterprise-plugins")
+	request.Cloud, _ = flags.GetBool("cloud")
+	request.Platform, _ = flags.GetString("platform")
+	request.ReturnAllVersions, _ = flags.GetBool("return-all-versions")
+	request.Page, _ = flags.GetInt("page")
+	request.PerPage, _ = flags.GetInt("per-page")
+
+	return client, request, nil
+}
+
+// queryPlugins writes the plugins matching the query flags and the given search term.
+func queryPlugins(command *cobra.Command, term string) error {
+	client, request, err := queryClientAndRequest(command)
+	if err != nil {
+		return err
+	}
+	request.Filter = term
+
+	var plugins []*model.Plugin
+	if command.Flags().Changed("page") {
+		plugins, err = client.GetPlugins(context.Background(), request)
+	} else {
+		plugins, err = fetchAllPlugins(client, request)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to query plugins")
+	}
+
+	format, _ := command.Flags().GetString("format")
+	if format == output.JSON {
+		if plugins == nil {
+			plugins = []*model.Plugin{}
+		}
+		return writeJSON(command.OutOrStdout(), plugins)
+	}
+
+	w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tVERSION\tMIN SERVER\tNAME\tAUTHOR\tSTAGE\tHOSTING\tENTERPRISE")
+	for _, plugin := range plugins {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n",
+			plugin.Manifest.Id,
+			plugin.Manifest.Version,
+			output.OrDash(plugin.Manifest.MinServerVersion),
+			plugin.Manifest.Name,
+			output.OrDash(string(plugin.AuthorType)),
+			output.OrDash(string(plugin.ReleaseStage)),
+			output.OrDash(string(plugin.Hosting)),
+			plugin.Enterprise,
+		)
+	}
+
+	return w.Flush()
+}
+
+// fetchAllPlugins walks every page of plugins matching the given request.
+func fetchAllPlugins(client *api.Client, request *api.GetPluginsRequest) ([]*model.Plugin, error) {
+	var plugins []*model.Plugin
+
+	iterator := client.IteratePlugins(request)
+	for iterator.Next(context.Background()) {
+		plugins = append(plugins, iterator.Plugin())
+	}
+	if err := iterator.Err(); err != nil {
+		return nil, err
+	}
+
+	return plugins, nil
+}
+
+// writePluginDetails writes the details of the given plugin as aligned key-value pairs.
+func writePluginDetails(w io.Writer, plugin *model.Plugin) {
+	var labels []string
+	for _, label := range plugin.Labels {
+		labels = append(labels, label.Name)
+	}
+
+	var size string
+	if plugin.Size > 0 {
+		size = fmt.Sprintf("%d bytes", plugin.Size)
+	}
+
+	details := [][2]string{
+		{"ID", plugin.Manifest.Id},
+		{"Name", plugin.Manifest.Name},
+		{"Version", plugin.Manifest.Version},
+		{"Min server version", plugin.Manifest.MinServerVersion},
+		{"Description", plugin.Manifest.Description},
+		{"Author type", string(plugin.AuthorType)},
+		{"Release stage", string(plugin.ReleaseStage)},
+		{"Hosting", string(plugin.Hosting)},
+		{"Enterprise", fmt.Sprint(plugin.Enterprise)},
+		{"Labels", strings.Join(labels, ", ")},
+		{"Homepage", plugin.HomepageURL},
+		{"Release notes", plugin.ReleaseNotesURL},
+		{"Download URL", plugin.DownloadURL},
+		{"Download size", size},
+		{"SHA-256", plugin.SHA256},
+		{"Updated at", plugin.UpdatedAt.Format(time.RFC3339)},
+		{"Source", plugin.Source},
+	}
+
+	for _, detail := range details {
+		fmt.Fprintf(w, "%s:\t%s\n", detail[0], output.OrDash(detail[1]))
+	}
+}
+
+const (
+	comparisonOnlyFirst  = "only in first"
+	comparisonOnlySecond = "only in second"
+	comparisonDifferent  = "different"
+)
+
+// pluginComparison describes how the versions of a plugin differ between two marketplaces.
+type pluginComparison struct {
+	ID            string `json:"id"`
+	Versions      string `json:"versions"`
+	OtherVersions string `json:"other_versions"`
+	Status        string `json:"status"`
+}
+
+// comparePlugins returns the plugins whose versions differ between the two lists, sorted by id.
+func comparePlugins(plugins, otherPlugins []*model.Plugin) []pluginComparison {
+	versions := pluginVersions(plugins)
+	otherVersions := pluginVersions(otherPlugins)
+
+	var ids []string
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	for id := range otherVersions {
+		if _, ok := versions[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	differences := []pluginComparison{}
+	for _, id := range ids {
+		comparison := pluginComparison{
+			ID:            id,
+			Versions:      versions[id],
+			OtherVersions: otherVersions[id],
+		}
+
+		switch {
+		case comparison.OtherVersions == "":
+			comparison.Status = comparisonOnlyFirst
+		case comparison.Versions == "":
+			comparison.Status = comparisonOnlySecond
+		case comparison.Versions != comparison.OtherVersions:
+			comparison.Status = comparisonDifferent
+		default:
+			continue
+		}
+
+		differences = append(differences, comparison)
+	}
+
+	return differences
+}
+
+// pluginVersions maps each plugin id to the comma-separated versions listed for it, in order.
+func pluginVersions(plugins []*model.Plugin) map[string]string {
+	versions := make(map[string]string)
+	for _, plugin := range plugins {
+		if versions[plugin.Manifest.Id] != "" {
+			versions[plugin.Manifest.Id] += ", "
+		}
+		versions[plugin.Manifest.Id] += plugin.Manifest.Version
+	}
+
+	return versions
+}
+
+// writeJSON writes the given value as indented JSON.
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/api"
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/store"
+	"github.com/mattermost/mattermost-marketplace/internal/testlib"
+)
+
+func makePlugin(id, version string) *model.Plugin {
+	return &model.Plugin{
+		DownloadURL: "https://example.com/" + id + "-" + version + ".tar.gz",
+		Manifest: &mattermostModel.Manifest{
+			Id:      id,
+			Name:    id,
+			Version: version,
+		},
+	}
+}
+
+func TestComparePlugins(t *testing.T) {
+	t.Run("identical", func(t *testing.T) {
+		plugins := []*model.Plugin{makePlugin("jira", "3.0.0"), makePlugin("todo", "0.3.0")}
+		assert.Empty(t, comparePlugins(plugins, plugins))
+	})
+
+	t.Run("differences", func(t *testing.T) {
+		plugins := []*model.Plugin{
+			makePlugin("todo", "0.3.0"),
+			makePlugin("jira", "3.0.0"),
+			makePlugin("github", "2.0.0"),
+			makePlugin("github", "1.0.0"),
+			makePlugin("zoom", "1.0.0"),
+		}
+		otherPlugins := []*model.Plugin{
+			makePlugin("jira", "3.1.0"),
+			makePlugin("github", "2.0.0"),
+			makePlugin("zoom", "1.0.0"),
+			makePlugin("autolink", "1.0.0"),
+		}
+
+		assert.Equal(t, []pluginComparison{
+			{ID: "autolink", OtherVersions: "1.0.0", Status: comparisonOnlySecond},
+			{ID: "github", Versions: "2.0.0, 1.0.0", OtherVersions: "2.0.0", Status: comparisonDifferent},
+			{ID: "jira", Versions: "3.0.0", OtherVersions: "3.1.0", Status: comparisonDifferent},
+			{ID: "todo", Versions: "0.3.0", Status: comparisonOnlyFirst},
+		}, comparePlugins(plugins, otherPlugins))
+	})
+}
+
+func setupMarketplace(t *testing.T, plugins []*model.Plugin) string {
+	t.Helper()
+
+	logger := testlib.MakeLogger(t)
+	staticStore, err := store.NewStatic(plugins, logger)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:  staticStore,
+		Logger: logger,
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	return ts.URL
+}
+
+func TestQuery(t *testing.T) {
+	marketplaceURL := setupMarketplace(t, []*model.Plugin{makePlugin("jira", "3.0.0"), makePlugin("todo", "0.3.0")})
+	otherMarketplaceURL := setupMarketplace(t, []*model.Plugin{makePlugin("jira", "3.1.0"), makePlugin("todo", "0.3.0")})
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+
+		output := &bytes.Buffer{}
+		rootCmd.SetArgs(append([]string{"query"}, args...))
+		rootCmd.SetOut(output)
+		require.NoError(t, rootCmd.Execute())
+
+		return output.String()
+	}
+
+	t.Run("search as json", func(t *testing.T) {
+		output := run(t, "search", "jira", "--url", marketplaceURL, "--format", "json", "--per-page", "1")
+
+		var plugins []*model.Plugin
+		require.NoError(t, json.Unmarshal([]byte(output), &plugins))
+		require.Len(t, plugins, 1)
+		assert.Equal(t, "jira", plugins[0].Manifest.Id)
+	})
+
+	t.Run("compare as table", func(t *testing.T) {
+		output := run(t, "compare", otherMarketplaceURL, "--url", marketplaceURL, "--format", "table", "--per-page", "1")
+
+		assert.Contains(t, output, "STATUS")
+		assert.Regexp(t, `jira\s+3\.0\.0\s+3\.1\.0\s+different`, output)
+		assert.NotContains(t, output, "todo")
+	})
+}
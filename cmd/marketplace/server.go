@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -28,9 +29,12 @@ var (
 func init() {
 	instanceID = model.NewId()
 
-	serverCmd.PersistentFlags().String("database", "plugins.json", "The read-only JSON file backing the server.")
+	serverCmd.PersistentFlags().String("database", "plugins.json", "The read-only JSON file backing the server, or an HTTP(S) URL from which to fetch it.")
+	serverCmd.PersistentFlags().Duration("poll-interval", 5*time.Minute, "How often to check a remote database for changes. Set to 0 to disable.")
 	serverCmd.PersistentFlags().String("listen", ":8085", "The interface and port on which to listen.")
 	serverCmd.PersistentFlags().String("upstream", upstreamURL, "An upstream marketplace server with which to merge results.")
+	serverCmd.PersistentFlags().String("name", "local", "The name identifying plugins served from the database when merged with an upstream marketplace.")
+	serverCmd.PersistentFlags().StringArray("pin", nil, "Pin a plugin to a version or range, e.g. com.mattermost.plugin-jira=3.2.0. May be repeated.")
 	serverCmd.PersistentFlags().Bool("debug", false, "Whether to output debug logs.")
 }
 
@@ -45,31 +49,69 @@ var serverCmd = &cobra.Command{
 			logger.SetLevel(logrus.DebugLevel)
 		}
 
-		database, _ := command.Flags().GetString("database")
-		databaseFile, err := os.Open(database)
-		if err != nil {
-			return errors.Wrapf(err, "failed to open %s", database)
-		}
-		defer databaseFile.Close()
+		pollCtx, cancelPoll := context.WithCancel(context.Background())
+		defer cancelPoll()
 
 		var apiStore store.Store
 
-		apiStore, err = store.NewStaticFromReader(databaseFile, logger)
-		if err != nil {
-			return errors.Wrap(err, "failed to initialize store")
+		database, _ := command.Flags().GetString("database")
+		if strings.HasPrefix(database, "http://") || strings.HasPrefix(database, "https://") {
+			remoteStore, err := store.NewRemote(database, logger)
+			if err != nil {
+				return errors.Wrap(err, "failed to initialize remote store")
+			}
+
+			pollInterval, _ := command.Flags().GetDuration("poll-interval")
+			if pollInterval > 0 {
+				logger.WithField("database", database).Infof("Polling remote database every %s", pollInterval)
+				go remoteStore.Poll(pollCtx, pollInterval)
+			}
+
+			apiStore = remoteStore
+		} else {
+			databaseFile, err := os.Open(database)
+			if err != nil {
+				return errors.Wrapf(err, "failed to open %s", database)
+			}
+			defer databaseFile.Close()
+
+			staticStore, err := store.NewStaticFromReader(databaseFile, logger)
+			if err != nil {
+				return errors.Wrap(err, "failed to initialize store")
+			}
+
+			apiStore = staticStore
 		}
 
+		name, _ := command.Flags().GetString("name")
+		stores := []store.Store{store.Named(apiStore, name)}
+
 		upstreamURL, _ := command.Flags().GetString("upstream")
 		if upstreamURL != "" {
-			var upstreamStore *store.Proxy
-			upstreamStore, err = store.NewProxy(upstreamURL, logger)
+			upstreamStore, err := store.NewProxy(upstreamURL, logger)
 			if err != nil {
 				return errors.Wrap(err, "failed to initialize upstream store")
 			}
 
 			logger.WithField("upstream", upstreamURL).Info("Proxying to upstream marketplace")
 
-			apiStore = store.NewMerged(logger, apiStore, upstreamStore)
+			stores = append(stores, upstreamStore)
+		}
+
+		pinFlags, _ := command.Flags().GetStringArray("pin")
+		pins, err := store.ParseVersionPins(pinFlags)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse version pins")
+		}
+
+		if len(stores) > 1 || len(pins) > 0 {
+			mergedStore := store.NewMerged(logger, stores...)
+			if len(pins) > 0 {
+				logger.WithField("pins", pinFlags).Info("Pinning plugin versions")
+				mergedStore.SetVersionPins(pins)
+			}
+
+			apiStore = mergedStore
 		}
 
 		logger := logger.WithField("instance", instanceID)
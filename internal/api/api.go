@@ -9,4 +9,5 @@ func Register(rootRouter *mux.Router, context *Context) {
 	initPlugins(apiRouter, context)
 	initLabels(apiRouter, context)
 	initHealthCheck(apiRouter, context)
+	initOpenAPI(apiRouter, context)
 }
@@ -1,29 +1,91 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/blang/semver"
 	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-marketplace/internal/model"
 )
 
+// defaultPerPage is the page size used when iterating over plugins without an explicit page size.
+const defaultPerPage = 100
+
 // Client is the programmatic interface to the Plugin Marketplace API.
 type Client struct {
 	Address    string
 	httpClient *http.Client
+	header     http.Header
+	userAgent  string
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient configures the client to send requests using the given http.Client.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout configures the client to give up on requests taking longer than the given timeout.
+//
+// The timeout applies to a copy of the configured http.Client, leaving the original untouched.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// WithHeader configures the client to send the given header with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithUserAgent configures the client to identify itself with the given User-Agent.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// defaultUserAgent identifies the client and the build it was compiled from.
+func defaultUserAgent() string {
+	version := buildTag
+	if version == "" {
+		version = "dev"
+	}
+
+	return "mattermost-marketplace-client/" + version
 }
 
 // NewClient creates a client to the Plugin Marketplace at the given address.
-func NewClient(address string) *Client {
-	return &Client{
+func NewClient(address string, options ...ClientOption) *Client {
+	c := &Client{
 		Address:    address,
 		httpClient: &http.Client{},
+		header:     http.Header{},
+		userAgent:  defaultUserAgent(),
+	}
+
+	for _, option := range options {
+		option(c)
 	}
+
+	return c
 }
 
 // closeBody ensures the Body of an http.Response is properly closed.
@@ -38,12 +100,53 @@ func (c *Client) buildURL(urlPath string) string {
 	return fmt.Sprintf("%s/%s", strings.TrimRight(c.Address, "/"), strings.TrimLeft(urlPath, "/"))
 }
 
-func (c *Client) doGet(u string) (*http.Response, error) {
-	return c.httpClient.Get(u)
+// errorFromResponse builds an *Error from an unsuccessful response, decoding the error body if
+// the server sent one.
+func errorFromResponse(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		// A malformed body still leaves the status code to report.
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr.ErrorResponse)
+	}
+
+	return apiErr
+}
+
+func (c *Client) doGet(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	for key, values := range c.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	return c.httpClient.Do(req)
+}
+
+// getJSON fetches the given API path, decoding a successful JSON response into result.
+func (c *Client) getJSON(ctx context.Context, u string, result interface{}) error {
+	resp, err := c.doGet(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return errorFromResponse(resp)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+
+	return nil
 }
 
 // GetPlugins fetches the list of plugins from the configured server.
-func (c *Client) GetPlugins(request *GetPluginsRequest) ([]*model.Plugin, error) {
+func (c *Client) GetPlugins(ctx context.Context, request *GetPluginsRequest) ([]*model.Plugin, error) {
 	u, err := url.Parse(c.buildURL("/api/v1/plugins"))
 	if err != nil {
 		return nil, err
@@ -51,7 +154,7 @@ func (c *Client) GetPlugins(request *GetPluginsRequest) ([]*model.Plugin, error)
 
 	request.ApplyToURL(u)
 
-	resp, err := c.doGet(u.String())
+	resp, err := c.doGet(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
@@ -61,29 +164,149 @@ func (c *Client) GetPlugins(request *GetPluginsRequest) ([]*model.Plugin, error)
 	case http.StatusOK:
 		return model.PluginsFromReader(resp.Body)
 	default:
-		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+		return nil, errorFromResponse(resp)
 	}
 }
 
-// GetPlugin fetches all the specified version of a single plugin
-func (c *Client) GetPlugin(request *GetPluginsRequest, pluginid string) ([]*model.Plugin, error) {
-	u, err := url.Parse(c.buildURL("/api/v1/plugin/" + pluginid))
+// GetPlugin fetches the latest version of the given plugin compatible with the given request,
+// returning nil if there is none.
+//
+// The request may be nil, and its paging and plugin id parameters are ignored.
+func (c *Client) GetPlugin(ctx context.Context, pluginID string, request *GetPluginsRequest) (*model.Plugin, error) {
+	pluginRequest := GetPluginsRequest{}
+	if request != nil {
+		pluginRequest = *request
+	}
+	pluginRequest.Page = 0
+	pluginRequest.PerPage = model.AllPerPage
+	pluginRequest.PluginID = pluginID
+	pluginRequest.ReturnAllVersions = false
+
+	plugins, err := c.GetPlugins(ctx, &pluginRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	request.ApplyToURL(u)
+	if len(plugins) == 0 {
+		return nil, nil
+	}
 
-	resp, err := c.doGet(u.String())
+	return plugins[0], nil
+}
+
+// CheckForUpdate fetches the latest version of the given plugin compatible with the given
+// request, returning nil if it is not newer than the installed version.
+func (c *Client) CheckForUpdate(ctx context.Context, pluginID, installedVersion string, request *GetPluginsRequest) (*model.Plugin, error) {
+	installed, err := semver.Parse(installedVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse installed version %s", installedVersion)
+	}
+
+	plugin, err := c.GetPlugin(ctx, pluginID, request)
 	if err != nil {
 		return nil, err
 	}
-	defer closeBody(resp)
+	if plugin == nil {
+		return nil, nil
+	}
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return model.PluginsFromReader(resp.Body)
-	default:
-		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	latest, err := semver.Parse(plugin.Manifest.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse version of plugin %s", pluginID)
+	}
+
+	if !latest.GT(installed) {
+		return nil, nil
 	}
+
+	return plugin, nil
+}
+
+// GetLabels fetches all labels that may be attached to plugins.
+func (c *Client) GetLabels(ctx context.Context) ([]model.Label, error) {
+	var labels []model.Label
+	if err := c.getJSON(ctx, c.buildURL("/api/v1/labels"), &labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// GetHealth fetches the health of the configured server.
+func (c *Client) GetHealth(ctx context.Context) (*HealthCheckResponse, error) {
+	health := &HealthCheckResponse{}
+	if err := c.getJSON(ctx, c.buildURL("/api/v1/health"), health); err != nil {
+		return nil, err
+	}
+
+	return health, nil
+}
+
+// IteratePlugins returns an iterator over every page of plugins matching the given request,
+// starting at the requested page.
+//
+// A non-positive page size is replaced with a default page size.
+func (c *Client) IteratePlugins(request *GetPluginsRequest) *PluginIterator {
+	pageRequest := *request
+	if pageRequest.PerPage <= 0 {
+		pageRequest.PerPage = defaultPerPage
+	}
+
+	return &PluginIterator{
+		client:  c,
+		request: pageRequest,
+	}
+}
+
+// PluginIterator walks the pages of plugins matching a request, fetching each page on demand.
+//
+//	iterator := client.IteratePlugins(request)
+//	for iterator.Next(ctx) {
+//		fmt.Println(iterator.Plugin().Manifest.Id)
+//	}
+//	if err := iterator.Err(); err != nil {
+//		return err
+//	}
+type PluginIterator struct {
+	client  *Client
+	request GetPluginsRequest
+	page    []*model.Plugin
+	plugin  *model.Plugin
+	done    bool
+	err     error
+}
+
+// Next advances to the next plugin, fetching the next page if needed. It returns false once all
+// plugins have been visited or an error occurred.
+func (it *PluginIterator) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.plugin = nil
+			return false
+		}
+
+		plugins, err := it.client.GetPlugins(ctx, &it.request)
+		if err != nil {
+			it.err = errors.Wrapf(err, "failed to fetch page %d", it.request.Page)
+			continue
+		}
+
+		it.page = plugins
+		it.done = len(plugins) < it.request.PerPage
+		it.request.Page++
+	}
+
+	it.plugin, it.page = it.page[0], it.page[1:]
+
+	return true
+}
+
+// Plugin returns the current plugin.
+func (it *PluginIterator) Plugin() *model.Plugin {
+	return it.plugin
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *PluginIterator) Err() error {
+	return it.err
 }
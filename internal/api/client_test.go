@@ -1,9 +1,23 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
 )
 
 func TestBuildURL(t *testing.T) {
@@ -40,3 +54,288 @@ func TestBuildURL(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorFromResponse(t *testing.T) {
+	t.Run("error body", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"code":"invalid_parameter","message":"unknown platform linux-arm","request_id":"id","parameter":"platform"}`)),
+		}
+
+		err := errorFromResponse(resp)
+		assert.EqualError(t, err, "failed with status code 400: unknown platform linux-arm")
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, &Error{
+			StatusCode: http.StatusBadRequest,
+			ErrorResponse: ErrorResponse{
+				Code:      ErrorCodeInvalidParameter,
+				Message:   "unknown platform linux-arm",
+				RequestID: "id",
+				Parameter: "platform",
+			},
+		}, apiErr)
+	})
+
+	t.Run("no error body", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       io.NopCloser(strings.NewReader("<html>Bad Gateway</html>")),
+		}
+
+		err := errorFromResponse(resp)
+		assert.EqualError(t, err, "failed with status code 502")
+	})
+}
+
+// fakeMarketplace serves the given plugins, filtered by id and paged as requested, recording
+// every request.
+type fakeMarketplace struct {
+	plugins  []*model.Plugin
+	requests []*http.Request
+}
+
+func (fm *fakeMarketplace) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fm.requests = append(fm.requests, r)
+
+	filter, err := ParsePluginFilter(r.URL)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var plugins []*model.Plugin
+	for _, plugin := range fm.plugins {
+		if filter.PluginID == "" || filter.PluginID == plugin.Manifest.Id {
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	if filter.PerPage != model.AllPerPage {
+		start := min(filter.Page*filter.PerPage, len(plugins))
+		end := min(start+filter.PerPage, len(plugins))
+		plugins = plugins[start:end]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(plugins)
+}
+
+func makePlugin(id, version string) *model.Plugin {
+	return &model.Plugin{
+		DownloadURL: "https://example.com/" + id + "-" + version + ".tar.gz",
+		Manifest: &mattermostModel.Manifest{
+			Id:      id,
+			Version: version,
+		},
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	marketplace := &fakeMarketplace{}
+	ts := httptest.NewServer(marketplace)
+	defer ts.Close()
+
+	t.Run("defaults", func(t *testing.T) {
+		client := NewClient(ts.URL)
+
+		_, err := client.GetPlugins(context.Background(), &GetPluginsRequest{})
+		require.NoError(t, err)
+
+		request := marketplace.requests[len(marketplace.requests)-1]
+		assert.Equal(t, defaultUserAgent(), request.Header.Get("User-Agent"))
+		assert.True(t, strings.HasPrefix(request.Header.Get("User-Agent"), "mattermost-marketplace-client/"))
+	})
+
+	t.Run("headers and user agent", func(t *testing.T) {
+		client := NewClient(ts.URL,
+			WithHeader("X-Request-Source", "test"),
+			WithHeader("X-Request-Source", "client"),
+			WithUserAgent("internal-tool/1.0"),
+		)
+
+		_, err := client.GetPlugins(context.Background(), &GetPluginsRequest{})
+		require.NoError(t, err)
+
+		request := marketplace.requests[len(marketplace.requests)-1]
+		assert.Equal(t, []string{"test", "client"}, request.Header.Values("X-Request-Source"))
+		assert.Equal(t, "internal-tool/1.0", request.Header.Get("User-Agent"))
+	})
+
+	t.Run("http client and timeout", func(t *testing.T) {
+		httpClient := &http.Client{}
+		client := NewClient(ts.URL, WithHTTPClient(httpClient), WithTimeout(time.Minute))
+
+		assert.Equal(t, time.Minute, client.httpClient.Timeout)
+		assert.Zero(t, httpClient.Timeout, "the given http client must not be modified")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer slow.Close()
+
+		_, err := NewClient(slow.URL, WithTimeout(10*time.Millisecond)).GetPlugins(context.Background(), &GetPluginsRequest{})
+		require.Error(t, err)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewClient(ts.URL).GetPlugins(ctx, &GetPluginsRequest{})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestClientEndpoints(t *testing.T) {
+	router := mux.NewRouter()
+	Register(router, &Context{
+		Logger: logrus.New(),
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	t.Run("labels", func(t *testing.T) {
+		labels, err := client.GetLabels(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, model.AllLabels, labels)
+	})
+
+	t.Run("health", func(t *testing.T) {
+		health, err := client.GetHealth(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "pass", health.Status)
+		assert.Equal(t, buildTag, health.ReleaseID)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := NewClient(ts.URL + "/missing").GetLabels(context.Background())
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	})
+}
+
+func TestClientGetPlugin(t *testing.T) {
+	marketplace := &fakeMarketplace{
+		plugins: []*model.Plugin{
+			makePlugin("jira", "3.0.0"),
+			makePlugin("todo", "0.3.0"),
+		},
+	}
+	ts := httptest.NewServer(marketplace)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	t.Run("found", func(t *testing.T) {
+		request := &GetPluginsRequest{ServerVersion: "9.11.0", PerPage: 10, PluginID: "ignored"}
+		plugin, err := client.GetPlugin(context.Background(), "todo", request)
+		require.NoError(t, err)
+		assert.Equal(t, marketplace.plugins[1], plugin)
+
+		query := marketplace.requests[len(marketplace.requests)-1].URL.Query()
+		assert.Equal(t, "todo", query.Get("plugin_id"))
+		assert.Equal(t, "9.11.0", query.Get("server_version"))
+		assert.Equal(t, "ignored", request.PluginID, "the given request must not be modified")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		plugin, err := client.GetPlugin(context.Background(), "missing", nil)
+		require.NoError(t, err)
+		assert.Nil(t, plugin)
+	})
+
+	t.Run("update available", func(t *testing.T) {
+		plugin, err := client.CheckForUpdate(context.Background(), "todo", "0.2.0", nil)
+		require.NoError(t, err)
+		assert.Equal(t, marketplace.plugins[1], plugin)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		plugin, err := client.CheckForUpdate(context.Background(), "todo", "0.3.0", nil)
+		require.NoError(t, err)
+		assert.Nil(t, plugin)
+
+		plugin, err = client.CheckForUpdate(context.Background(), "todo", "0.4.0", nil)
+		require.NoError(t, err)
+		assert.Nil(t, plugin)
+	})
+
+	t.Run("invalid installed version", func(t *testing.T) {
+		_, err := client.CheckForUpdate(context.Background(), "todo", "latest", nil)
+		require.Error(t, err)
+	})
+}
+
+func TestPluginIterator(t *testing.T) {
+	var plugins []*model.Plugin
+	for i := 0; i < 5; i++ {
+		plugins = append(plugins, makePlugin(fmt.Sprintf("plugin-%d", i), "1.0.0"))
+	}
+
+	iterate := func(t *testing.T, client *Client, request *GetPluginsRequest) ([]*model.Plugin, error) {
+		t.Helper()
+
+		var result []*model.Plugin
+		iterator := client.IteratePlugins(request)
+		for iterator.Next(context.Background()) {
+			result = append(result, iterator.Plugin())
+		}
+		assert.Nil(t, iterator.Plugin())
+
+		return result, iterator.Err()
+	}
+
+	for _, tc := range []struct {
+		name          string
+		plugins       []*model.Plugin
+		request       *GetPluginsRequest
+		expected      []*model.Plugin
+		expectedPages int
+	}{
+		{"no plugins", nil, &GetPluginsRequest{PerPage: 2}, nil, 1},
+		{"partial last page", plugins, &GetPluginsRequest{PerPage: 2}, plugins, 3},
+		{"full last page", plugins[:4], &GetPluginsRequest{PerPage: 2}, plugins[:4], 3},
+		{"starting page", plugins, &GetPluginsRequest{Page: 1, PerPage: 2}, plugins[2:], 2},
+		{"default page size", plugins, &GetPluginsRequest{PerPage: model.AllPerPage}, plugins, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			marketplace := &fakeMarketplace{plugins: tc.plugins}
+			ts := httptest.NewServer(marketplace)
+			defer ts.Close()
+
+			result, err := iterate(t, NewClient(ts.URL), tc.request)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+			assert.Len(t, marketplace.requests, tc.expectedPages)
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		marketplace := &fakeMarketplace{plugins: plugins}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "1" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			marketplace.ServeHTTP(w, r)
+		}))
+		defer ts.Close()
+
+		result, err := iterate(t, NewClient(ts.URL), &GetPluginsRequest{PerPage: 2})
+		assert.Equal(t, plugins[:2], result)
+
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	})
+}
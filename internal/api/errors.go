@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error codes identify the kind of failure described by an ErrorResponse.
+const (
+	ErrorCodeInvalidParameter = "invalid_parameter"
+	ErrorCodeInternal         = "internal_error"
+)
+
+// ErrorResponse is the body of every unsuccessful response from the API.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+	Parameter string `json:"parameter,omitempty"` // The offending query parameter, if any
+}
+
+// Error is returned by the Client when the API responds unsuccessfully.
+type Error struct {
+	StatusCode int
+	ErrorResponse
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("failed with status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("failed with status code %d: %s", e.StatusCode, e.Message)
+}
+
+// ParameterError describes a query parameter that could not be parsed or is out of range.
+type ParameterError struct {
+	Parameter string
+	Err       error
+}
+
+func newParameterError(parameter string, err error) *ParameterError {
+	return &ParameterError{
+		Parameter: parameter,
+		Err:       err,
+	}
+}
+
+func (e *ParameterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// outputError writes the given error response, annotated with the request id, as JSON.
+func outputError(c *Context, w http.ResponseWriter, statusCode int, response ErrorResponse) {
+	response.RequestID = c.RequestID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	outputJSON(c, w, response)
+}
@@ -12,7 +12,8 @@ var (
 	buildHashShort = ""
 )
 
-type healthCheckResponse struct {
+// HealthCheckResponse describes the service and the build it is running.
+type HealthCheckResponse struct {
 	Status      string                       `json:"status"`
 	Version     string                       `json:"version"`
 	ReleaseID   string                       `json:"releaseID"`
@@ -40,7 +41,7 @@ func handleHealthCheck(c *Context, w http.ResponseWriter, _ *http.Request) {
 	details := make(map[string]map[string]string)
 	details["buildInfo"] = buildInfo
 
-	response := healthCheckResponse{
+	response := HealthCheckResponse{
 		Status:      "pass",
 		Version:     "1",
 		ReleaseID:   buildTag,
@@ -15,7 +15,7 @@ func parseInt(u *url.URL, name string, defaultValue int) (int, error) {
 
 	value, err := strconv.Atoi(valueStr)
 	if err != nil {
-		return 0, errors.Wrapf(err, "failed to parse %s as integer", name)
+		return 0, newParameterError(name, errors.Wrapf(err, "failed to parse %s as integer", name))
 	}
 
 	return value, nil
@@ -29,7 +29,7 @@ func parseBool(u *url.URL, name string, defaultValue bool) (bool, error) {
 
 	value, err := strconv.ParseBool(valueStr)
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to parse %s as boolean", name)
+		return false, newParameterError(name, errors.Wrapf(err, "failed to parse %s as boolean", name))
 	}
 
 	return value, nil
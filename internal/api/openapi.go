@@ -0,0 +1,30 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPISpecification is the authoritative description of the API, served as-is.
+//
+//go:embed openapi.json
+var openAPISpecification []byte
+
+// initOpenAPI registers the specification endpoint on the given router.
+func initOpenAPI(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	apiRouter.Handle("/openapi.json", addContext(handleGetOpenAPI)).Methods(http.MethodGet)
+}
+
+// handleGetOpenAPI responds to GET /api/v1/openapi.json, returning the OpenAPI specification of the API.
+func handleGetOpenAPI(c *Context, w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPISpecification); err != nil {
+		c.Logger.WithError(err).Error("failed to write specification")
+	}
+}
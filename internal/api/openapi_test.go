@@ -0,0 +1,369 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/api"
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// openAPISpec is the subset of an OpenAPI 3 document needed to check the API against it.
+type openAPISpec struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters []struct {
+		Name    string      `json:"name"`
+		In      string      `json:"in"`
+		Example interface{} `json:"example"`
+	} `json:"parameters"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *openAPISchema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Enum                 []interface{}             `json:"enum"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	Items                *openAPISchema            `json:"items"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+}
+
+func loadOpenAPISpec(t *testing.T) *openAPISpec {
+	t.Helper()
+
+	data, err := os.ReadFile("openapi.json")
+	require.NoError(t, err)
+
+	spec := &openAPISpec{}
+	require.NoError(t, json.Unmarshal(data, spec))
+
+	return spec
+}
+
+// responseSchema returns the schema documented for the given response to the given operation.
+func (spec *openAPISpec) responseSchema(t *testing.T, path, statusCode, contentType string) *openAPISchema {
+	t.Helper()
+
+	operation, ok := spec.Paths[path]["get"]
+	require.True(t, ok, "missing GET %s", path)
+	content, ok := operation.Responses[statusCode].Content[contentType]
+	require.True(t, ok, "missing %s %s response for GET %s", statusCode, contentType, path)
+
+	return content.Schema
+}
+
+// validate checks the given decoded JSON value against the given schema, returning any violations.
+//
+// Only the keywords used by openapi.json are supported.
+func (spec *openAPISpec) validate(schema *openAPISchema, value interface{}, path string) []string {
+	if schema.Ref != "" {
+		return spec.validate(spec.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")], value, path)
+	}
+
+	var violations []string
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: %v is not one of %v", path, value, schema.Enum))
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: expected object, got %T", path, value))
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required property %s", path, name))
+			}
+		}
+
+		var additionalProperties *openAPISchema
+		allowAdditionalProperties := len(schema.AdditionalProperties) == 0 || string(schema.AdditionalProperties) == "true"
+		if !allowAdditionalProperties && string(schema.AdditionalProperties) != "false" {
+			additionalProperties = &openAPISchema{}
+			if err := json.Unmarshal(schema.AdditionalProperties, additionalProperties); err != nil {
+				return append(violations, fmt.Sprintf("%s: invalid additionalProperties: %s", path, err))
+			}
+		}
+
+		for name, propertyValue := range object {
+			propertySchema, ok := schema.Properties[name]
+			switch {
+			case ok:
+				violations = append(violations, spec.validate(propertySchema, propertyValue, path+"."+name)...)
+			case additionalProperties != nil:
+				violations = append(violations, spec.validate(additionalProperties, propertyValue, path+"."+name)...)
+			case !allowAdditionalProperties:
+				violations = append(violations, fmt.Sprintf("%s: undocumented property %s", path, name))
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: expected array, got %T", path, value))
+		}
+
+		for i, item := range array {
+			violations = append(violations, spec.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			violations = append(violations, fmt.Sprintf("%s: expected string, got %T", path, value))
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			violations = append(violations, fmt.Sprintf("%s: expected integer, got %v", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violations = append(violations, fmt.Sprintf("%s: expected boolean, got %T", path, value))
+		}
+	}
+
+	return violations
+}
+
+// getJSON fetches the given URL, decoding the JSON response body.
+func getJSON(t *testing.T, u string) (*http.Response, interface{}) {
+	t.Helper()
+
+	return getJSONWithStatus(t, u, http.StatusOK)
+}
+
+// getJSONWithStatus fetches the given URL, expecting the given status code and decoding the JSON
+// response body.
+func getJSONWithStatus(t *testing.T, u string, statusCode int) (*http.Response, interface{}) {
+	t.Helper()
+
+	resp, err := http.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, statusCode, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var value interface{}
+	require.NoError(t, json.Unmarshal(body, &value))
+
+	return resp, value
+}
+
+func TestOpenAPISpecification(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	t.Run("served", func(t *testing.T) {
+		client, tearDown := setupAPI(t, nil)
+		defer tearDown()
+
+		resp, err := http.Get(client.Address + "/api/v1/openapi.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		served, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		expected, err := os.ReadFile("openapi.json")
+		require.NoError(t, err)
+		assert.Equal(t, expected, served)
+		assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+	})
+
+	t.Run("every route is documented", func(t *testing.T) {
+		router := mux.NewRouter()
+		api.Register(router, &api.Context{
+			Logger: logrus.New(),
+		})
+
+		var routes []string
+		err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			if route.GetHandler() == nil {
+				return nil
+			}
+
+			pathTemplate, err := route.GetPathTemplate()
+			if err != nil {
+				return err
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				return err
+			}
+
+			for _, method := range methods {
+				routes = append(routes, strings.ToLower(method)+" "+pathTemplate)
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		var documented []string
+		for path, operations := range spec.Paths {
+			for method := range operations {
+				documented = append(documented, method+" "+path)
+			}
+		}
+
+		sort.Strings(routes)
+		sort.Strings(documented)
+		assert.Equal(t, documented, routes)
+	})
+
+	t.Run("client sends every documented plugins parameter", func(t *testing.T) {
+		var documented []string
+		for _, parameter := range spec.Paths["/api/v1/plugins"]["get"].Parameters {
+			assert.Equal(t, "query", parameter.In)
+			documented = append(documented, parameter.Name)
+		}
+
+		u, err := url.Parse("http://localhost/api/v1/plugins")
+		require.NoError(t, err)
+		(&api.GetPluginsRequest{}).ApplyToURL(u)
+
+		var sent []string
+		for name := range u.Query() {
+			sent = append(sent, name)
+		}
+
+		sort.Strings(documented)
+		sort.Strings(sent)
+		assert.Equal(t, documented, sent)
+	})
+
+	t.Run("server parses every documented plugins parameter", func(t *testing.T) {
+		query := url.Values{}
+		for _, parameter := range spec.Paths["/api/v1/plugins"]["get"].Parameters {
+			require.NotNil(t, parameter.Example, "missing example for %s", parameter.Name)
+			query.Set(parameter.Name, fmt.Sprint(parameter.Example))
+		}
+
+		filter, err := api.ParsePluginFilter(&url.URL{RawQuery: query.Encode()})
+		require.NoError(t, err)
+
+		// The examples are chosen to differ from the defaults, so every field must be set.
+		filterValue := reflect.ValueOf(*filter)
+		for i := 0; i < filterValue.NumField(); i++ {
+			assert.False(t, filterValue.Field(i).IsZero(), "%s is not parsed from any documented parameter", filterValue.Type().Field(i).Name)
+		}
+	})
+
+	t.Run("plugins response conforms", func(t *testing.T) {
+		promotedAt := time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)
+		plugin := &model.Plugin{
+			HomepageURL:     "https://github.com/mattermost/mattermost-plugin-todo",
+			IconData:        "data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=",
+			DownloadURL:     "https://github.com/mattermost/mattermost-plugin-todo/releases/download/v0.3.0/com.mattermost.plugin-todo-0.3.0.tar.gz",
+			ReleaseNotesURL: "https://github.com/mattermost/mattermost-plugin-todo/releases/v0.3.0",
+			Hosting:         model.OnPrem,
+			AuthorType:      model.Community,
+			ReleaseStage:    model.Beta,
+			Enterprise:      true,
+			Signature:       "signature",
+			SHA256:          "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c",
+			Size:            1024,
+			RepoName:        "mattermost-plugin-todo",
+			Manifest: &mattermostModel.Manifest{
+				Id:               "com.mattermost.plugin-todo",
+				Name:             "Todo",
+				Description:      "A plugin to track Todo issues in a list and send you daily reminders about your Todo list.",
+				Version:          "0.3.0",
+				MinServerVersion: "5.12.0",
+			},
+			Platforms: model.PlatformBundles{
+				LinuxAmd64: model.PlatformBundleMetadata{
+					DownloadURL: "https://plugins.releases.mattermost.com/release/mattermost-plugin-todo-v0.3.0-linux-amd64.tar.gz",
+					Signature:   "signature for linux",
+					SHA256:      "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730",
+					Size:        2048,
+				},
+			},
+			UpdatedAt:  time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			PromotedAt: &promotedAt,
+		}
+
+		client, tearDown := setupAPI(t, []*model.Plugin{plugin})
+		defer tearDown()
+
+		for _, platform := range []string{"", model.LinuxAmd64} {
+			u, err := url.Parse(client.Address + "/api/v1/plugins")
+			require.NoError(t, err)
+			(&api.GetPluginsRequest{PerPage: model.AllPerPage, EnterprisePlugins: true, Platform: platform}).ApplyToURL(u)
+
+			resp, value := getJSON(t, u.String())
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			require.Len(t, value, 1)
+			assert.Empty(t, spec.validate(spec.responseSchema(t, "/api/v1/plugins", "200", "application/json"), value, "plugins"))
+		}
+	})
+
+	t.Run("plugins error response conforms", func(t *testing.T) {
+		client, tearDown := setupAPI(t, nil)
+		defer tearDown()
+
+		resp, value := getJSONWithStatus(t, client.Address+"/api/v1/plugins?platform=linux-arm", http.StatusBadRequest)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Empty(t, spec.validate(spec.responseSchema(t, "/api/v1/plugins", "400", "application/json"), value, "error"))
+	})
+
+	t.Run("labels response conforms", func(t *testing.T) {
+		client, tearDown := setupAPI(t, nil)
+		defer tearDown()
+
+		resp, value := getJSON(t, client.Address+"/api/v1/labels")
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Empty(t, spec.validate(spec.responseSchema(t, "/api/v1/labels", "200", "application/json"), value, "labels"))
+	})
+
+	t.Run("health response conforms", func(t *testing.T) {
+		client, tearDown := setupAPI(t, nil)
+		defer tearDown()
+
+		resp, value := getJSON(t, client.Address+"/api/v1/health")
+		assert.Equal(t, "application/health+json", resp.Header.Get("Content-Type"))
+		assert.Empty(t, spec.validate(spec.responseSchema(t, "/api/v1/health", "200", "application/health+json"), value, "health"))
+	})
+
+	t.Run("validation catches undocumented properties", func(t *testing.T) {
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(`[{"name":"Beta","description":"","url":"","color":"","undocumented":true}]`), &value))
+		assert.NotEmpty(t, spec.validate(spec.responseSchema(t, "/api/v1/labels", "200", "application/json"), value, "labels"))
+	})
+}
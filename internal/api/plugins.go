@@ -3,8 +3,11 @@ package api
 import (
 	"net/http"
 	"net/url"
+	"slices"
 
+	"github.com/blang/semver"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/mattermost/mattermost-marketplace/internal/model"
 )
@@ -19,20 +22,43 @@ func initPlugins(apiRouter *mux.Router, context *Context) {
 	pluginsRouter.Handle("", addContext(handleGetPlugins)).Methods(http.MethodGet)
 }
 
+// maxPerPage is the largest page size that may be requested, other than model.AllPerPage.
+const maxPerPage = 200
+
+// ParsePluginFilter parses and validates the query string parameters describing a plugin filter.
+//
+// Invalid parameters are reported as a *ParameterError.
 func ParsePluginFilter(u *url.URL) (*model.PluginFilter, error) {
 	page, err := parseInt(u, "page", 0)
 	if err != nil {
 		return nil, err
 	}
+	if page < 0 {
+		return nil, newParameterError("page", errors.New("page must not be negative"))
+	}
 
 	perPage, err := parseInt(u, "per_page", 100)
 	if err != nil {
 		return nil, err
 	}
+	if perPage < model.AllPerPage || perPage > maxPerPage {
+		return nil, newParameterError("per_page", errors.Errorf("per_page must be between 0 and %d, or %d for all plugins", maxPerPage, model.AllPerPage))
+	}
 
 	filter := u.Query().Get("filter")
+
 	serverVersion := u.Query().Get("server_version")
+	if serverVersion != "" {
+		if _, err = semver.Parse(serverVersion); err != nil {
+			return nil, newParameterError("server_version", errors.Wrapf(err, "failed to parse server_version %s", serverVersion))
+		}
+	}
+
 	platform := u.Query().Get("platform")
+	if platform != "" && !slices.Contains(model.ServerPlatforms, platform) {
+		return nil, newParameterError("platform", errors.Errorf("unknown platform %s", platform))
+	}
+
 	pluginID := u.Query().Get("plugin_id")
 
 	enterprisePlugins, err := parseBool(u, "enterprise_plugins", false)
@@ -68,16 +94,30 @@ func handleGetPlugins(c *Context, w http.ResponseWriter, r *http.Request) {
 	filter, err := ParsePluginFilter(r.URL)
 	if err != nil {
 		c.Logger.WithError(err).Error("failed to parse paging parameters")
-		w.WriteHeader(http.StatusBadRequest)
+
+		response := ErrorResponse{
+			Code:    ErrorCodeInvalidParameter,
+			Message: err.Error(),
+		}
+		var parameterErr *ParameterError
+		if errors.As(err, &parameterErr) {
+			response.Parameter = parameterErr.Parameter
+		}
+
+		outputError(c, w, http.StatusBadRequest, response)
 		return
 	}
 
 	plugins, err := c.Store.GetPlugins(filter)
 	if err != nil {
 		c.Logger.WithError(err).Error("failed to query plugins")
-		w.WriteHeader(http.StatusInternalServerError)
+		outputError(c, w, http.StatusInternalServerError, ErrorResponse{
+			Code:    ErrorCodeInternal,
+			Message: "failed to query plugins",
+		})
 		return
 	}
+
 	if plugins == nil {
 		plugins = []*model.Plugin{}
 	}
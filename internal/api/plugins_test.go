@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gorilla/mux"
 	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-marketplace/internal/api"
@@ -38,12 +40,19 @@ func setupAPI(t *testing.T, plugins []*model.Plugin) (*api.Client, func()) {
 	}
 }
 
+// failingStore is a store whose every query fails.
+type failingStore struct{}
+
+func (failingStore) GetPlugins(*model.PluginFilter) ([]*model.Plugin, error) {
+	return nil, errors.New("backend unavailable")
+}
+
 func TestPlugins(t *testing.T) {
 	t.Run("no plugins", func(t *testing.T) {
 		client, tearDown := setupAPI(t, nil)
 		defer tearDown()
 
-		plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+		plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 			Page:    0,
 			PerPage: 10,
 		})
@@ -96,6 +105,92 @@ func TestPlugins(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, http.StatusOK, resp.StatusCode)
 		})
+
+		t.Run("error body", func(t *testing.T) {
+			client, tearDown := setupAPI(t, nil)
+			defer tearDown()
+
+			resp, err := http.Get(fmt.Sprintf("%s/api/v1/plugins?page=invalid", client.Address))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var errorResponse api.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResponse))
+			require.Equal(t, api.ErrorCodeInvalidParameter, errorResponse.Code)
+			require.Equal(t, "page", errorResponse.Parameter)
+			require.Contains(t, errorResponse.Message, "failed to parse page as integer")
+			require.NotEmpty(t, errorResponse.RequestID)
+		})
+
+		for _, tc := range []struct {
+			name      string
+			request   *api.GetPluginsRequest
+			parameter string
+		}{
+			{"negative page", &api.GetPluginsRequest{Page: -1, PerPage: 10}, "page"},
+			{"perPage below -1", &api.GetPluginsRequest{PerPage: -2}, "per_page"},
+			{"perPage over cap", &api.GetPluginsRequest{PerPage: 201}, "per_page"},
+			{"unknown platform", &api.GetPluginsRequest{PerPage: 10, Platform: "linux-arm"}, "platform"},
+			{"well-formed unknown platform", &api.GetPluginsRequest{PerPage: 10, Platform: "foo-bar"}, "platform"},
+			{"invalid server_version", &api.GetPluginsRequest{PerPage: 10, ServerVersion: "a"}, "server_version"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				client, tearDown := setupAPI(t, nil)
+				defer tearDown()
+
+				plugins, err := client.GetPlugins(context.Background(), tc.request)
+				require.Nil(t, plugins)
+
+				var apiErr *api.Error
+				require.ErrorAs(t, err, &apiErr)
+				require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+				require.Equal(t, api.ErrorCodeInvalidParameter, apiErr.Code)
+				require.Equal(t, tc.parameter, apiErr.Parameter)
+				require.NotEmpty(t, apiErr.RequestID)
+				require.Contains(t, err.Error(), "failed with status code 400")
+			})
+		}
+
+		t.Run("valid edges", func(t *testing.T) {
+			client, tearDown := setupAPI(t, nil)
+			defer tearDown()
+
+			for _, request := range []*api.GetPluginsRequest{
+				{PerPage: 0},
+				{PerPage: 200},
+				{PerPage: model.AllPerPage},
+				{PerPage: 10, Platform: model.DarwinAmd64},
+			} {
+				_, err := client.GetPlugins(context.Background(), request)
+				require.NoError(t, err)
+			}
+		})
+
+		t.Run("store failure", func(t *testing.T) {
+			logger := testlib.MakeLogger(t)
+
+			router := mux.NewRouter()
+			api.Register(router, &api.Context{
+				Store:  failingStore{},
+				Logger: logger,
+			})
+			ts := httptest.NewServer(router)
+			defer ts.Close()
+
+			plugins, err := api.NewClient(ts.URL).GetPlugins(context.Background(), &api.GetPluginsRequest{PerPage: 10})
+			require.Nil(t, plugins)
+
+			var apiErr *api.Error
+			require.ErrorAs(t, err, &apiErr)
+			require.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+			require.Equal(t, api.ErrorCodeInternal, apiErr.Code)
+			require.Empty(t, apiErr.Parameter)
+
+			// The underlying failure is logged rather than leaked to the client.
+			require.NotContains(t, apiErr.Message, "backend unavailable")
+		})
 	})
 
 	t.Run("plugins", func(t *testing.T) {
@@ -226,10 +321,14 @@ func TestPlugins(t *testing.T) {
 				MinServerVersion: "5.12.0",
 			},
 			Signature: "signature6",
+			SHA256:    "sha256 of plugin6",
+			Size:      6000,
 			Platforms: model.PlatformBundles{
 				LinuxAmd64: model.PlatformBundleMetadata{
 					DownloadURL: "https://plugins.releases.mattermost.com/release/mattermost-plugin-todo-v0.3.0-linux-amd64.tar.gz",
 					Signature:   "signature6 for linux",
+					SHA256:      "sha256 of plugin6 for linux",
+					Size:        6001,
 				},
 				DarwinAmd64: model.PlatformBundleMetadata{
 					DownloadURL: "https://plugins.releases.mattermost.com/release/mattermost-plugin-todo-v0.3.0-osx-amd64.tar.gz",
@@ -278,7 +377,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				Page:    0,
 				PerPage: 2,
 			})
@@ -290,7 +389,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				Page:    1,
 				PerPage: 2,
 			})
@@ -302,7 +401,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage:       3,
 				ServerVersion: "5.18.0",
 			})
@@ -314,7 +413,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage:       3,
 				ServerVersion: "5.15.0",
 			})
@@ -327,7 +426,7 @@ func TestPlugins(t *testing.T) {
 
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage:       3,
 				ServerVersion: "5.14.0",
 			})
@@ -339,7 +438,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: 3,
 			})
 			require.NoError(t, err)
@@ -350,7 +449,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				Filter:  "matterpoll",
 				PerPage: 3,
 			})
@@ -362,7 +461,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, []*model.Plugin{plugin1V1Min515, plugin1V2Min515, plugin1V3Min515, plugin2V1Min516, plugin3V2Min516, plugin3V3Min517, plugin4V1NoMin})
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				Filter:        "matterpoll",
 				ServerVersion: "5.16.0",
 				PerPage:       3,
@@ -375,7 +474,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				Filter:        "matterpoll",
 				ServerVersion: "5.17.0",
 				PerPage:       3,
@@ -388,7 +487,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, append(allPlugins, plugin4V1NoMin))
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: -1,
 			})
 			require.NoError(t, err)
@@ -399,7 +498,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion:     "5.24.0",
 				PerPage:           -1,
 				EnterprisePlugins: false,
@@ -412,7 +511,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion:     "5.25.0",
 				PerPage:           -1,
 				EnterprisePlugins: false,
@@ -425,7 +524,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion:     "5.25.0",
 				PerPage:           -1,
 				EnterprisePlugins: true,
@@ -438,7 +537,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion:     "5.26.0",
 				PerPage:           -1,
 				EnterprisePlugins: true,
@@ -451,7 +550,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion: "5.26.0",
 				PerPage:       -1,
 				Filter:        "todo",
@@ -462,8 +561,10 @@ func TestPlugins(t *testing.T) {
 			require.NotEqual(t, plugin6WithPlatform.DownloadURL, plugins[0].DownloadURL)
 			require.Equal(t, plugin6WithPlatform.Platforms.LinuxAmd64.DownloadURL, plugins[0].DownloadURL)
 			require.Equal(t, plugin6WithPlatform.Platforms.LinuxAmd64.Signature, plugins[0].Signature)
+			require.Equal(t, plugin6WithPlatform.Platforms.LinuxAmd64.SHA256, plugins[0].SHA256)
+			require.Equal(t, plugin6WithPlatform.Platforms.LinuxAmd64.Size, plugins[0].Size)
 
-			plugins, err = client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err = client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion: "5.26.0",
 				PerPage:       -1,
 				Filter:        "todo",
@@ -475,7 +576,7 @@ func TestPlugins(t *testing.T) {
 			require.Equal(t, plugin6WithPlatform.Platforms.DarwinAmd64.DownloadURL, plugins[0].DownloadURL)
 			require.Equal(t, plugin6WithPlatform.Platforms.DarwinAmd64.Signature, plugins[0].Signature)
 
-			plugins, err = client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err = client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion: "5.26.0",
 				PerPage:       -1,
 				Filter:        "todo",
@@ -492,23 +593,25 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				ServerVersion: "5.26.0",
 				PerPage:       -1,
 				Filter:        "todo",
-				Platform:      "linux-arm",
+				Platform:      model.LinuxArm64,
 			})
 			require.NoError(t, err)
 			require.Len(t, plugins, 1)
 			require.Equal(t, plugin6WithPlatform.DownloadURL, plugins[0].DownloadURL)
 			require.Equal(t, plugin6WithPlatform.Signature, plugins[0].Signature)
+			require.Equal(t, plugin6WithPlatform.SHA256, plugins[0].SHA256)
+			require.Equal(t, plugin6WithPlatform.Size, plugins[0].Size)
 		})
 
 		t.Run("cloud only plugin is return for cloud instance", func(t *testing.T) {
 			client, tearDown := setupAPI(t, append(allPlugins, plugin7CloudOnly))
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: -1,
 				Cloud:   true,
 			})
@@ -520,7 +623,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, append(allPlugins, plugin7CloudOnly))
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: -1,
 				Cloud:   false,
 			})
@@ -532,7 +635,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, append(allPlugins, plugin8OnPremOnly))
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: -1,
 				Cloud:   true,
 			})
@@ -544,7 +647,7 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, append(allPlugins, plugin8OnPremOnly))
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage: -1,
 				Cloud:   false,
 			})
@@ -556,14 +659,14 @@ func TestPlugins(t *testing.T) {
 			client, tearDown := setupAPI(t, allPlugins)
 			defer tearDown()
 
-			plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err := client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage:       -1,
 				ServerVersion: "1",
 			})
 			require.Error(t, err)
 			require.Nil(t, plugins)
 
-			plugins, err = client.GetPlugins(&api.GetPluginsRequest{
+			plugins, err = client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 				PerPage:       -1,
 				ServerVersion: "a",
 			})
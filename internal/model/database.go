@@ -0,0 +1,96 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SchemaVersion is the newest version of the plugins database schema understood by this binary.
+//
+// Databases predating schema versions are a bare list of plugins, see inferSchemaVersion.
+const SchemaVersion = 2
+
+// listSchemaVersion is the schema version of a bare list of plugins already migrated from labels to
+// metadata and platform bundles, the last schema predating schema versions.
+const listSchemaVersion = 2
+
+// Database is the plugins database, as stored in plugins.json.
+type Database struct {
+	SchemaVersion int       `json:"schema_version"`
+	Plugins       []*Plugin `json:"plugins"`
+}
+
+// UnsupportedSchemaVersionError is returned when reading a database with a newer schema version
+// than this binary understands, and so might silently drop or misinterpret its fields.
+type UnsupportedSchemaVersionError struct {
+	SchemaVersion int
+}
+
+func (e *UnsupportedSchemaVersionError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the supported schema version %d", e.SchemaVersion, SchemaVersion)
+}
+
+// DatabaseFromReader decodes a json-encoded plugins database from the given io.Reader, accepting
+// both a versioned database and a bare list of plugins.
+func DatabaseFromReader(reader io.Reader) (*Database, error) {
+	database := &Database{Plugins: []*Plugin{}}
+
+	var data json.RawMessage
+	err := json.NewDecoder(reader).Decode(&data)
+	if err == io.EOF {
+		database.SchemaVersion = SchemaVersion
+		return database, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &database.Plugins)
+		database.SchemaVersion = inferSchemaVersion(database.Plugins)
+	} else {
+		err = json.Unmarshal(data, database)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if database.SchemaVersion > SchemaVersion {
+		return nil, &UnsupportedSchemaVersionError{SchemaVersion: database.SchemaVersion}
+	}
+
+	if database.Plugins == nil {
+		database.Plugins = []*Plugin{}
+	}
+
+	return database, nil
+}
+
+// inferSchemaVersion returns the schema version of a bare list of plugins predating schema versions.
+//
+// Lists whose entries all have an author type and release stage were written by the generator since
+// the migration of labels to metadata, which also added the platform bundles, and so need no
+// migration. Other lists are read as schema version 0, and an empty list as the newest.
+func inferSchemaVersion(plugins []*Plugin) int {
+	if len(plugins) == 0 {
+		return SchemaVersion
+	}
+
+	for _, plugin := range plugins {
+		if plugin.AuthorType == "" || plugin.ReleaseStage == "" {
+			return 0
+		}
+	}
+
+	return listSchemaVersion
+}
+
+// DatabaseToWriter encodes a json-encoded plugins database to the given io.Writer.
+func DatabaseToWriter(w io.Writer, database *Database) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(database)
+}
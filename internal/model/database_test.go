@@ -0,0 +1,84 @@
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	mattermostModel "github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseFromReader(t *testing.T) {
+	t.Run("empty database", func(t *testing.T) {
+		database, err := DatabaseFromReader(bytes.NewReader(nil))
+		require.NoError(t, err)
+		assert.Equal(t, &Database{SchemaVersion: SchemaVersion, Plugins: []*Plugin{}}, database)
+
+		database, err = DatabaseFromReader(strings.NewReader(`[]`))
+		require.NoError(t, err)
+		assert.Equal(t, &Database{SchemaVersion: SchemaVersion, Plugins: []*Plugin{}}, database)
+	})
+
+	t.Run("list of plugins", func(t *testing.T) {
+		database, err := DatabaseFromReader(strings.NewReader(` [{"manifest": {"id": "a"}}]`))
+		require.NoError(t, err)
+		assert.Equal(t, &Database{Plugins: []*Plugin{{Manifest: &mattermostModel.Manifest{Id: "a"}}}}, database)
+	})
+
+	t.Run("list of migrated plugins", func(t *testing.T) {
+		database, err := DatabaseFromReader(strings.NewReader(`[
+			{"author_type": "community", "release_stage": "beta", "manifest": {"id": "a"}},
+			{"author_type": "mattermost", "release_stage": "production", "manifest": {"id": "b"}}
+		]`))
+		require.NoError(t, err)
+		assert.Equal(t, 2, database.SchemaVersion)
+		assert.Len(t, database.Plugins, 2)
+
+		database, err = DatabaseFromReader(strings.NewReader(`[
+			{"author_type": "community", "release_stage": "beta", "manifest": {"id": "a"}},
+			{"manifest": {"id": "b"}}
+		]`))
+		require.NoError(t, err)
+		assert.Equal(t, 0, database.SchemaVersion)
+	})
+
+	t.Run("versioned database", func(t *testing.T) {
+		database, err := DatabaseFromReader(strings.NewReader(`{"schema_version": 1, "plugins": [{"manifest": {"id": "a"}}]}`))
+		require.NoError(t, err)
+		assert.Equal(t, &Database{SchemaVersion: 1, Plugins: []*Plugin{{Manifest: &mattermostModel.Manifest{Id: "a"}}}}, database)
+	})
+
+	t.Run("versioned database without plugins", func(t *testing.T) {
+		database, err := DatabaseFromReader(strings.NewReader(`{"schema_version": 1}`))
+		require.NoError(t, err)
+		assert.Equal(t, &Database{SchemaVersion: 1, Plugins: []*Plugin{}}, database)
+	})
+
+	t.Run("newer schema version", func(t *testing.T) {
+		_, err := DatabaseFromReader(strings.NewReader(`{"schema_version": 99, "plugins": []}`))
+		require.EqualError(t, err, "database schema version 99 is newer than the supported schema version 2")
+		assert.IsType(t, &UnsupportedSchemaVersionError{}, err)
+
+		_, err = PluginsFromReader(strings.NewReader(`{"schema_version": 99, "plugins": []}`))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid database", func(t *testing.T) {
+		_, err := DatabaseFromReader(strings.NewReader(`{"schema_version": "1"}`))
+		require.Error(t, err)
+	})
+}
+
+func TestDatabaseToWriter(t *testing.T) {
+	var b bytes.Buffer
+	err := DatabaseToWriter(&b, &Database{SchemaVersion: SchemaVersion, Plugins: []*Plugin{}})
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"schema_version\": 2,\n  \"plugins\": []\n}\n", b.String())
+
+	database, err := DatabaseFromReader(&b)
+	require.NoError(t, err)
+	assert.Equal(t, &Database{SchemaVersion: SchemaVersion, Plugins: []*Plugin{}}, database)
+}
@@ -14,6 +14,7 @@ var AllLabels = []Label{
 	BetaLabel,
 	ExperimentalLabel,
 	EnterpriseLabel,
+	PinnedLabel,
 }
 
 var PartnerLabel = Label{
@@ -44,3 +45,8 @@ var EnterpriseLabel = Label{
 	Description: "This plugin requires a Professional or Enterprise subscription.",
 	URL:         "https://mattermost.com/pricing/",
 }
+
+var PinnedLabel = Label{
+	Name:        "Pinned",
+	Description: "This plugin is pinned to a vetted version by the Marketplace operator. Newer releases may be available.",
+}
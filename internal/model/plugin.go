@@ -38,21 +38,27 @@ type Plugin struct {
 	DownloadURL     string                    `json:"download_url"`
 	ReleaseNotesURL string                    `json:"release_notes_url"`
 	Labels          []Label                   `json:"labels,omitempty"`
-	Hosting         HostingType               `json:"hosting"`       // Indicated if the plugin is limited to a certain hosting type
-	AuthorType      AuthorType                `json:"author_type"`   // The maintainer of the plugin
-	ReleaseStage    ReleaseStage              `json:"release_stage"` // The stage in the software release cycle that the plugin is in
-	Enterprise      bool                      `json:"enterprise"`    // Indicated if the plugin is an enterprise plugin
-	Signature       string                    `json:"signature"`     // A signature of a plugin saved in base64 encoding.
+	Hosting         HostingType               `json:"hosting"`          // Indicated if the plugin is limited to a certain hosting type
+	AuthorType      AuthorType                `json:"author_type"`      // The maintainer of the plugin
+	ReleaseStage    ReleaseStage              `json:"release_stage"`    // The stage in the software release cycle that the plugin is in
+	Enterprise      bool                      `json:"enterprise"`       // Indicated if the plugin is an enterprise plugin
+	Signature       string                    `json:"signature"`        // A signature of a plugin saved in base64 encoding.
+	SHA256          string                    `json:"sha256,omitempty"` // The hex-encoded SHA-256 checksum of the plugin bundle
+	Size            int64                     `json:"size,omitempty"`   // The size of the plugin bundle in bytes
 	RepoName        string                    `json:"repo_name"`
 	Manifest        *mattermostModel.Manifest `json:"manifest"`
 	Platforms       PlatformBundles           `json:"platforms"`
-	UpdatedAt       time.Time                 `json:"updated_at"` // The point in time this release of the plugin was added to the Plugin Marketplace
+	UpdatedAt       time.Time                 `json:"updated_at"`            // The point in time this release of the plugin was added to the Plugin Marketplace
+	PromotedAt      *time.Time                `json:"promoted_at,omitempty"` // The point in time the release stage of this release was last changed, if ever
+	Source          string                    `json:"source,omitempty"`      // The store from which the plugin was served, if merged from multiple stores
 }
 
 // PlatformBundleMetadata holds the necessary data to fetch and verify a plugin built for a specific platform
 type PlatformBundleMetadata struct {
 	DownloadURL string `json:"download_url,omitempty"`
 	Signature   string `json:"signature,omitempty"`
+	SHA256      string `json:"sha256,omitempty"` // The hex-encoded SHA-256 checksum of the bundle
+	Size        int64  `json:"size,omitempty"`   // The size of the bundle in bytes
 }
 
 type PlatformBundles struct {
@@ -63,10 +69,16 @@ type PlatformBundles struct {
 
 const (
 	LinuxAmd64   = "linux-amd64"
+	LinuxArm64   = "linux-arm64"
 	DarwinAmd64  = "darwin-amd64"
+	DarwinArm64  = "darwin-arm64"
 	WindowsAmd64 = "windows-amd64"
 )
 
+// ServerPlatforms are the platforms a Mattermost server may report when querying for plugins.
+// Platforms without dedicated bundles are served the default bundle.
+var ServerPlatforms = []string{LinuxAmd64, LinuxArm64, DarwinAmd64, DarwinArm64, WindowsAmd64}
+
 // PluginFromReader decodes a json-encoded cluster from the given io.Reader.
 func PluginFromReader(reader io.Reader) (*Plugin, error) {
 	cluster := Plugin{}
@@ -79,17 +91,16 @@ func PluginFromReader(reader io.Reader) (*Plugin, error) {
 	return &cluster, nil
 }
 
-// PluginsFromReader decodes a json-encoded list of plugins from the given io.Reader.
+// PluginsFromReader decodes a json-encoded list of plugins, or the plugins of a json-encoded plugins
+// database, from the given io.Reader. Databases with a newer schema version than understood are
+// refused.
 func PluginsFromReader(reader io.Reader) ([]*Plugin, error) {
-	plugins := []*Plugin{}
-	decoder := json.NewDecoder(reader)
-
-	err := decoder.Decode(&plugins)
-	if err != nil && err != io.EOF {
+	database, err := DatabaseFromReader(reader)
+	if err != nil {
 		return nil, err
 	}
 
-	return plugins, nil
+	return database.Plugins, nil
 }
 
 // PluginsToWriter encodes a json-encoded list of plugins to the given io.Writer.
@@ -106,26 +117,53 @@ func PluginsToWriter(w io.Writer, plugins []*Plugin) error {
 	return nil
 }
 
+// AddLabels attaches the labels derived from the plugin's metadata. Labels already present are
+// not added again, making it safe to call repeatedly, e.g. when merging stores. Derived labels no
+// longer matching the metadata, e.g. the beta label of a release since promoted to production,
+// are removed.
 func (p *Plugin) AddLabels() {
-	if p.AuthorType == Partner {
-		p.Labels = append(p.Labels, PartnerLabel)
+	derived := []struct {
+		label   Label
+		applies bool
+	}{
+		{PartnerLabel, p.AuthorType == Partner},
+		{CommunityLabel, p.AuthorType == Community},
+		{BetaLabel, p.ReleaseStage == Beta},
+		{ExperimentalLabel, p.ReleaseStage == Experimental},
+		{EnterpriseLabel, p.Enterprise},
 	}
 
-	if p.AuthorType == Community {
-		p.Labels = append(p.Labels, CommunityLabel)
+	stale := make(map[Label]bool)
+	for _, d := range derived {
+		if !d.applies {
+			stale[d.label] = true
+		}
 	}
 
-	if p.ReleaseStage == Beta {
-		p.Labels = append(p.Labels, BetaLabel)
+	// Rebuild rather than filter the labels in place, since copies of the plugin share them.
+	var labels []Label
+	for _, label := range p.Labels {
+		if !stale[label] {
+			labels = append(labels, label)
+		}
 	}
+	p.Labels = labels
 
-	if p.ReleaseStage == Experimental {
-		p.Labels = append(p.Labels, ExperimentalLabel)
+	for _, d := range derived {
+		if d.applies {
+			p.addLabel(d.label)
+		}
 	}
+}
 
-	if p.Enterprise {
-		p.Labels = append(p.Labels, EnterpriseLabel)
+func (p *Plugin) addLabel(label Label) {
+	for _, l := range p.Labels {
+		if l == label {
+			return
+		}
 	}
+
+	p.Labels = append(p.Labels, label)
 }
 
 // PluginFilter describes the parameters used to constrain a set of plugins.
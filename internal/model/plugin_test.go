@@ -236,3 +236,34 @@ func TestPluginsToWriter(t *testing.T) {
 		assert.Equal(t, expectedResult, b.String())
 	})
 }
+
+func TestAddLabels(t *testing.T) {
+	t.Run("no labels", func(t *testing.T) {
+		p := &Plugin{AuthorType: Mattermost, ReleaseStage: Production}
+		p.AddLabels()
+		assert.Empty(t, p.Labels)
+	})
+
+	t.Run("labels from metadata", func(t *testing.T) {
+		p := &Plugin{AuthorType: Community, ReleaseStage: Beta, Enterprise: true}
+		p.AddLabels()
+		assert.Equal(t, []Label{CommunityLabel, BetaLabel, EnterpriseLabel}, p.Labels)
+	})
+
+	t.Run("repeated calls don't duplicate labels", func(t *testing.T) {
+		p := &Plugin{AuthorType: Partner, ReleaseStage: Experimental}
+		p.AddLabels()
+		p.AddLabels()
+		assert.Equal(t, []Label{PartnerLabel, ExperimentalLabel}, p.Labels)
+	})
+
+	t.Run("stale labels are removed", func(t *testing.T) {
+		custom := Label{Name: "Custom"}
+		labels := []Label{custom, BetaLabel, EnterpriseLabel}
+
+		p := &Plugin{AuthorType: Mattermost, ReleaseStage: Production, Enterprise: true, Labels: labels}
+		p.AddLabels()
+		assert.Equal(t, []Label{custom, EnterpriseLabel}, p.Labels)
+		assert.Equal(t, []Label{custom, BetaLabel, EnterpriseLabel}, labels, "labels shared with copies must not be modified")
+	})
+}
@@ -0,0 +1,20 @@
+// Package output holds the output formats and helpers shared by the command line tools.
+package output
+
+const (
+	// Table renders results as aligned, human readable columns.
+	Table = "table"
+	// JSON renders results as indented JSON.
+	JSON = "json"
+	// Markdown renders results as a Markdown document.
+	Markdown = "markdown"
+)
+
+// OrDash substitutes a dash for empty values in tabular output.
+func OrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
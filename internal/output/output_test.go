@@ -0,0 +1,12 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrDash(t *testing.T) {
+	assert.Equal(t, "-", OrDash(""))
+	assert.Equal(t, "1.0.0", OrDash("1.0.0"))
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
@@ -11,9 +13,10 @@ import (
 //
 // If a plugin is present in multiple stores, the later version is preferred. If a plugin with
 // the same version is present in multiple stores, the one from the later store (as initialized)
-// is preferred.
+// is preferred. Version pins, if configured, take precedence over both rules.
 type Merged struct {
 	stores []Store
+	pins   VersionPins
 	logger logrus.FieldLogger
 }
 
@@ -25,10 +28,16 @@ func NewMerged(logger logrus.FieldLogger, stores ...Store) *Merged {
 	}
 }
 
+// SetVersionPins configures the versions to which plugins are pinned when resolving the latest
+// version of each plugin across stores.
+func (store *Merged) SetVersionPins(pins VersionPins) {
+	store.pins = pins
+}
+
 // GetPlugins fetches the given page of plugins. The first page is 0.
 func (store *Merged) GetPlugins(pluginFilter *model.PluginFilter) ([]*model.Plugin, error) {
-	// Short-circuit if only one store is configured.
-	if len(store.stores) == 1 {
+	// Short-circuit if only one store is configured and there are no pins to apply.
+	if len(store.stores) == 1 && len(store.pins) == 0 {
 		return store.stores[0].GetPlugins(pluginFilter)
 	}
 
@@ -36,20 +45,113 @@ func (store *Merged) GetPlugins(pluginFilter *model.PluginFilter) ([]*model.Plug
 	filter.Page = 0
 	filter.PerPage = model.AllPerPage
 
+	plugins, err := store.getPlugins(&filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pinned versions may be older than the latest version in any given store.
+	if len(store.pins) > 0 && !filter.ReturnAllVersions {
+		plugins, err = store.withPinnedVersions(plugins, &filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The same release may be served by multiple stores, so duplicates are left to the static
+	// store to resolve in favour of the later store rather than rejected.
+	if err = validatePlugins(plugins, store.logger); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize static store")
+	}
+
+	staticStore := &StaticStore{
+		plugins: plugins,
+		pins:    store.pins,
+		logger:  store.logger,
+	}
+
+	return staticStore.GetPlugins(pluginFilter)
+}
+
+// getPlugins queries every store with the given filter, annotating the plugins with their source
+// if there is more than one store to tell apart.
+func (store *Merged) getPlugins(filter *model.PluginFilter) ([]*model.Plugin, error) {
+	annotate := len(store.stores) > 1
+
 	plugins := []*model.Plugin{}
 	for i, store := range store.stores {
-		storePlugins, err := store.GetPlugins(&filter)
+		storePlugins, err := store.GetPlugins(filter)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to query store %d", i)
 		}
 
+		if annotate {
+			storePlugins = withSource(storePlugins, storeSource(store, i))
+		}
+
 		plugins = append(plugins, storePlugins...)
 	}
 
-	staticStore, err := NewStatic(plugins, store.logger)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to initialize static store")
+	return plugins, nil
+}
+
+// withPinnedVersions replaces the latest version of each pinned plugin among the given plugins
+// with all of its versions, queried from every store with a targeted filter, so that the pin may
+// resolve to an older version. Plugins that aren't pinned are left as is.
+func (store *Merged) withPinnedVersions(plugins []*model.Plugin, filter *model.PluginFilter) ([]*model.Plugin, error) {
+	var pinnedIDs []string
+	seen := make(map[string]bool)
+	result := make([]*model.Plugin, 0, len(plugins))
+	for _, plugin := range plugins {
+		pluginID := plugin.Manifest.Id
+		if _, ok := store.pins[pluginID]; !ok {
+			result = append(result, plugin)
+			continue
+		}
+
+		if !seen[pluginID] {
+			seen[pluginID] = true
+			pinnedIDs = append(pinnedIDs, pluginID)
+		}
 	}
 
-	return staticStore.GetPlugins(pluginFilter)
+	for _, pluginID := range pinnedIDs {
+		pinnedFilter := *filter
+		pinnedFilter.PluginID = pluginID
+		pinnedFilter.ReturnAllVersions = true
+
+		versions, err := store.getPlugins(&pinnedFilter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query versions of pinned plugin %s", pluginID)
+		}
+
+		result = append(result, versions...)
+	}
+
+	return result, nil
+}
+
+// storeSource describes the given store, falling back to its position if it cannot describe itself.
+func storeSource(store Store, i int) string {
+	if sourcer, ok := store.(Sourcer); ok && sourcer.Source() != "" {
+		return sourcer.Source()
+	}
+
+	return fmt.Sprintf("store %d", i)
+}
+
+// withSource annotates copies of the given plugins with the store from which they were served.
+func withSource(plugins []*model.Plugin, source string) []*model.Plugin {
+	if plugins == nil {
+		return nil
+	}
+
+	result := make([]*model.Plugin, 0, len(plugins))
+	for _, plugin := range plugins {
+		newRef := *plugin
+		newRef.Source = source
+		result = append(result, &newRef)
+	}
+
+	return result
 }
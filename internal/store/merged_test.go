@@ -184,12 +184,7 @@ func TestMerged(t *testing.T) {
 			PerPage: model.AllPerPage,
 		})
 		require.NoError(t, err)
-		assert.Equal(t, []*model.Plugin{
-			plugin1V3,
-			plugin2V1,
-			plugin3V3,
-			plugin4V1,
-		}, plugins)
+		assert.Equal(t, []*model.Plugin{plugin1V3, plugin2V1, plugin3V3, plugin4V1}, plugins)
 	})
 
 	t.Run("conflict-free merge", func(t *testing.T) {
@@ -210,10 +205,10 @@ func TestMerged(t *testing.T) {
 		})
 		require.NoError(t, err)
 		assert.Equal(t, []*model.Plugin{
-			plugin1V3,
-			plugin2V1,
-			plugin3V3,
-			plugin4V1,
+			servedFrom(plugin1V3, "store 0"),
+			servedFrom(plugin2V1, "store 1"),
+			servedFrom(plugin3V3, "store 1"),
+			servedFrom(plugin4V1, "store 1"),
 		}, plugins)
 	})
 
@@ -235,10 +230,10 @@ func TestMerged(t *testing.T) {
 		})
 		require.NoError(t, err)
 		assert.Equal(t, []*model.Plugin{
-			plugin1V3,
-			plugin2V1,
-			plugin3V3,
-			plugin4V1,
+			servedFrom(plugin1V3, "store 1"),
+			servedFrom(plugin2V1, "store 1"),
+			servedFrom(plugin3V3, "store 1"),
+			servedFrom(plugin4V1, "store 1"),
 		}, plugins)
 	})
 
@@ -262,8 +257,193 @@ func TestMerged(t *testing.T) {
 		})
 		require.NoError(t, err)
 		assert.Equal(t, []*model.Plugin{
-			plugin1V3,
-			plugin4V1Later,
+			servedFrom(plugin1V3, "store 2"),
+			servedFrom(plugin4V1Later, "store 1"),
+		}, plugins)
+	})
+
+	t.Run("pinned version wins across stores", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin3V1, plugin3V2}, logger)
+		require.NoError(t, err)
+		static2, err := NewStatic([]*model.Plugin{plugin1V3, plugin3V3}, logger)
+		require.NoError(t, err)
+
+		pins, err := ParseVersionPins([]string{"matterpoll=<1.3.0"})
+		require.NoError(t, err)
+
+		store := NewMerged(logger, static1, static2)
+		store.SetVersionPins(pins)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+		})
+		require.NoError(t, err)
+		require.Len(t, plugins, 2)
+		assert.Equal(t, servedFrom(plugin1V3, "store 1"), plugins[0])
+		assert.Equal(t, "1.2.0", plugins[1].Manifest.Version)
+		assert.Equal(t, []model.Label{model.PinnedLabel}, plugins[1].Labels)
+	})
+
+	t.Run("pins apply to a single store", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin1V2, plugin1V3}, logger)
+		require.NoError(t, err)
+
+		pins, err := ParseVersionPins([]string{"mattermost-plugin-demo=0.1.0"})
+		require.NoError(t, err)
+
+		store := NewMerged(logger, static1)
+		store.SetVersionPins(pins)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+		})
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, "0.1.0", plugins[0].Manifest.Version)
+		assert.Equal(t, []model.Label{model.PinnedLabel}, plugins[0].Labels)
+		assert.Empty(t, plugins[0].Source)
+	})
+
+	t.Run("unsatisfiable pin falls back to the latest version", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin1V2}, logger)
+		require.NoError(t, err)
+		static2, err := NewStatic([]*model.Plugin{plugin1V3}, logger)
+		require.NoError(t, err)
+
+		pins, err := ParseVersionPins([]string{"mattermost-plugin-demo=>=1.0.0"})
+		require.NoError(t, err)
+
+		store := NewMerged(logger, static1, static2)
+		store.SetVersionPins(pins)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.Plugin{servedFrom(plugin1V3, "store 1")}, plugins)
+	})
+
+	t.Run("pin resolving to the latest version is not labelled", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin1V2}, logger)
+		require.NoError(t, err)
+		static2, err := NewStatic([]*model.Plugin{plugin1V3}, logger)
+		require.NoError(t, err)
+
+		pins, err := ParseVersionPins([]string{"mattermost-plugin-demo=>=0.2.0"})
+		require.NoError(t, err)
+
+		store := NewMerged(logger, static1, static2)
+		store.SetVersionPins(pins)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.Plugin{servedFrom(plugin1V3, "store 1")}, plugins)
+	})
+
+	t.Run("all versions are only queried for pinned plugins", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin1V2, plugin2V1}, logger)
+		require.NoError(t, err)
+		static2, err := NewStatic([]*model.Plugin{plugin1V3, plugin3V1, plugin3V2}, logger)
+		require.NoError(t, err)
+		recording := &recordingStore{Store: static2}
+
+		pins, err := ParseVersionPins([]string{"mattermost-plugin-demo=0.2.0", "unknown-plugin=1.0.0"})
+		require.NoError(t, err)
+
+		store := NewMerged(logger, static1, recording)
+		store.SetVersionPins(pins)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+		})
+		require.NoError(t, err)
+		require.Len(t, plugins, 3)
+		assert.Equal(t, "0.2.0", plugins[0].Manifest.Version)
+		assert.Equal(t, []model.Label{model.PinnedLabel}, plugins[0].Labels)
+
+		require.Len(t, recording.filters, 2)
+		assert.False(t, recording.filters[0].ReturnAllVersions)
+		assert.Empty(t, recording.filters[0].PluginID)
+		assert.True(t, recording.filters[1].ReturnAllVersions)
+		assert.Equal(t, "mattermost-plugin-demo", recording.filters[1].PluginID)
+
+		recording.filters = nil
+		plugins, err = store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: model.AllPerPage,
+			Filter:  "matterpoll",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.Plugin{servedFrom(plugin3V2, "store 1")}, plugins)
+		require.Len(t, recording.filters, 1)
+		assert.False(t, recording.filters[0].ReturnAllVersions)
+	})
+
+	t.Run("named stores", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+
+		static1, err := NewStatic([]*model.Plugin{plugin1V1, plugin2V1}, logger)
+		require.NoError(t, err)
+		static1.SetSource("/srv/marketplace/plugins.json")
+		static2, err := NewStatic([]*model.Plugin{plugin1V3}, logger)
+		require.NoError(t, err)
+		static2.SetSource("https://api.integrations.mattermost.com")
+
+		store := NewMerged(logger, Named(static1, "local"), static2)
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{
+			Page:    0,
+			PerPage: 1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.Plugin{
+			servedFrom(plugin1V3, "https://api.integrations.mattermost.com"),
+		}, plugins)
+
+		plugins, err = store.GetPlugins(&model.PluginFilter{
+			Page:    1,
+			PerPage: 1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.Plugin{
+			servedFrom(plugin2V1, "local"),
 		}, plugins)
 	})
 }
+
+// servedFrom returns a copy of the given plugin as annotated by the merged store.
+func servedFrom(plugin *model.Plugin, source string) *model.Plugin {
+	newRef := *plugin
+	newRef.Source = source
+
+	return &newRef
+}
+
+// recordingStore records the filters with which the wrapped store is queried.
+type recordingStore struct {
+	Store
+	filters []model.PluginFilter
+}
+
+func (store *recordingStore) GetPlugins(filter *model.PluginFilter) ([]*model.Plugin, error) {
+	store.filters = append(store.filters, *filter)
+
+	return store.Store.GetPlugins(filter)
+}
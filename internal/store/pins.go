@@ -0,0 +1,37 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+// VersionPins constrains latest-version resolution for specific plugins, keyed by plugin id.
+//
+// A pinned plugin resolves to the newest version satisfying its pin rather than the newest
+// version overall, allowing an older, vetted release to be served even if upstream has moved on.
+type VersionPins map[string]semver.Range
+
+// ParseVersionPins parses pins of the form "<plugin id>=<version or range>", e.g.
+// "com.mattermost.plugin-jira=3.2.0" or "com.mattermost.plugin-jira=>=3.0.0 <3.3.0".
+func ParseVersionPins(pins []string) (VersionPins, error) {
+	result := make(VersionPins, len(pins))
+	for _, pin := range pins {
+		pluginID, constraint, found := strings.Cut(pin, "=")
+		pluginID = strings.TrimSpace(pluginID)
+		constraint = strings.TrimSpace(constraint)
+		if !found || pluginID == "" || constraint == "" {
+			return nil, errors.Errorf("invalid pin %q, expected <plugin id>=<version or range>", pin)
+		}
+
+		versionRange, err := semver.ParseRange(constraint)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse version range for pinned plugin %s", pluginID)
+		}
+
+		result[pluginID] = versionRange
+	}
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionPins(t *testing.T) {
+	t.Run("no pins", func(t *testing.T) {
+		pins, err := ParseVersionPins(nil)
+		require.NoError(t, err)
+		assert.Empty(t, pins)
+	})
+
+	t.Run("exact version", func(t *testing.T) {
+		pins, err := ParseVersionPins([]string{"com.mattermost.plugin-jira=3.2.0"})
+		require.NoError(t, err)
+		require.Contains(t, pins, "com.mattermost.plugin-jira")
+		assert.True(t, pins["com.mattermost.plugin-jira"](semver.MustParse("3.2.0")))
+		assert.False(t, pins["com.mattermost.plugin-jira"](semver.MustParse("3.2.1")))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		pins, err := ParseVersionPins([]string{"com.mattermost.plugin-jira=>=3.0.0 <3.3.0"})
+		require.NoError(t, err)
+		require.Contains(t, pins, "com.mattermost.plugin-jira")
+		assert.True(t, pins["com.mattermost.plugin-jira"](semver.MustParse("3.2.9")))
+		assert.False(t, pins["com.mattermost.plugin-jira"](semver.MustParse("3.3.0")))
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		_, err := ParseVersionPins([]string{"com.mattermost.plugin-jira"})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing plugin id", func(t *testing.T) {
+		_, err := ParseVersionPins([]string{"=3.2.0"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := ParseVersionPins([]string{"com.mattermost.plugin-jira=latest"})
+		assert.Error(t, err)
+	})
+}
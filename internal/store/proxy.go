@@ -1,6 +1,9 @@
 package store
 
 import (
+	"context"
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
@@ -11,6 +14,7 @@ import (
 // Proxy is a store that fetches its result from some remote marketplace server.
 type Proxy struct {
 	marketplaceURL string
+	client         *api.Client
 	logger         logrus.FieldLogger
 }
 
@@ -18,15 +22,19 @@ type Proxy struct {
 func NewProxy(marketplaceURL string, logger logrus.FieldLogger) (*Proxy, error) {
 	return &Proxy{
 		marketplaceURL: marketplaceURL,
+		client:         api.NewClient(marketplaceURL, api.WithTimeout(30*time.Second)),
 		logger:         logger.WithField("marketplace_url", marketplaceURL),
 	}, nil
 }
 
+// Source describes where the plugins originate from, i.e. the upstream marketplace URL.
+func (store *Proxy) Source() string {
+	return store.marketplaceURL
+}
+
 // GetPlugins fetches the given page of plugins. The first page is 0.
 func (store *Proxy) GetPlugins(pluginFilter *model.PluginFilter) ([]*model.Plugin, error) {
-	client := api.NewClient(store.marketplaceURL)
-
-	plugins, err := client.GetPlugins(&api.GetPluginsRequest{
+	plugins, err := store.client.GetPlugins(context.Background(), &api.GetPluginsRequest{
 		Page:              pluginFilter.Page,
 		PerPage:           pluginFilter.PerPage,
 		Filter:            pluginFilter.Filter,
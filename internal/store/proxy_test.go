@@ -124,3 +124,11 @@ func TestProxyGetPlugins(t *testing.T) {
 		}}, plugins)
 	})
 }
+
+func TestProxySource(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+
+	proxyStore, err := NewProxy("https://api.integrations.mattermost.com", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.integrations.mattermost.com", proxyStore.Source())
+}
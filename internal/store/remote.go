@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+)
+
+// Remote is a store backed by a plugins database fetched over HTTP(S), e.g. from an object
+// storage bucket.
+//
+// The database is refreshed on demand, periodically or lazily when queried, using conditional
+// requests. A new copy is only swapped in after it has been validated, so the last good copy
+// continues to be served if the remote is unreachable or serves an invalid database.
+type Remote struct {
+	databaseURL string
+	source      string
+	httpClient  *http.Client
+	logger      logrus.FieldLogger
+
+	current atomic.Pointer[StaticStore]
+
+	// refreshLock serializes refreshes, guarding the validators below.
+	refreshLock  sync.Mutex
+	etag         string
+	lastModified string
+
+	// refreshInterval is how long GetPlugins waits after the last check before refreshing the
+	// database in the background, if positive. lastChecked holds the time of the last check in
+	// Unix nanoseconds.
+	refreshInterval time.Duration
+	lastChecked     atomic.Int64
+}
+
+// NewRemote creates a new instance of a remote store, failing if the initial database cannot be
+// fetched.
+func NewRemote(databaseURL string, logger logrus.FieldLogger) (*Remote, error) {
+	source := redactURL(databaseURL)
+	store := &Remote{
+		databaseURL: databaseURL,
+		source:      source,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		logger:      logger.WithField("database_url", source),
+	}
+
+	if err := store.Refresh(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch initial database")
+	}
+	store.lastChecked.Store(time.Now().UnixNano())
+
+	return store, nil
+}
+
+// SetRefreshInterval makes GetPlugins refresh the database in the background once the given
+// interval has passed since the last check, for environments where Poll cannot run reliably, e.g.
+// a lambda function frozen between invocations.
+func (store *Remote) SetRefreshInterval(interval time.Duration) {
+	store.refreshInterval = interval
+}
+
+// Source describes where the plugins originate from, i.e. the database URL without any
+// credentials or query string, e.g. those of a presigned URL.
+func (store *Remote) Source() string {
+	return store.source
+}
+
+// redactURL strips the user info, query string and fragment from the given URL.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// Refresh fetches the database if it changed since the last refresh, swapping it in if valid.
+func (store *Remote) Refresh(ctx context.Context) error {
+	store.refreshLock.Lock()
+	defer store.refreshLock.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, store.databaseURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	if store.etag != "" {
+		req.Header.Set("If-None-Match", store.etag)
+	}
+	if store.lastModified != "" {
+		req.Header.Set("If-Modified-Since", store.lastModified)
+	}
+
+	resp, err := store.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch database")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		store.logger.Debug("Database not modified")
+		return nil
+	default:
+		return errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+
+	staticStore, err := NewStaticFromReader(resp.Body, store.logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize store from fetched database")
+	}
+	staticStore.SetSource(store.source)
+
+	store.current.Store(staticStore)
+	store.etag = resp.Header.Get("ETag")
+	store.lastModified = resp.Header.Get("Last-Modified")
+	store.logger.WithField("etag", store.etag).Info("Loaded database")
+
+	return nil
+}
+
+// Poll refreshes the database at the given interval until the given context is cancelled.
+//
+// Failures are logged, with the last good copy of the database continuing to be served.
+func (store *Remote) Poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Refresh(ctx); err != nil {
+				store.logger.WithError(err).Error("Failed to refresh database, keeping last good copy")
+			}
+		}
+	}
+}
+
+// GetPlugins fetches the given page of plugins. The first page is 0.
+func (store *Remote) GetPlugins(pluginFilter *model.PluginFilter) ([]*model.Plugin, error) {
+	store.refreshIfStale()
+
+	return store.current.Load().GetPlugins(pluginFilter)
+}
+
+// refreshIfStale starts refreshing the database in the background if the refresh interval has
+// passed since the last check.
+//
+// Only the first caller past the interval starts a refresh, and no caller waits for it, serving
+// the last good copy in the meantime. Failures are logged and not retried until the interval
+// passes again.
+func (store *Remote) refreshIfStale() {
+	if store.refreshInterval <= 0 {
+		return
+	}
+
+	lastChecked := store.lastChecked.Load()
+	now := time.Now()
+	if now.Sub(time.Unix(0, lastChecked)) < store.refreshInterval {
+		return
+	}
+
+	if !store.lastChecked.CompareAndSwap(lastChecked, now.UnixNano()) {
+		return
+	}
+
+	go func() {
+		if err := store.Refresh(context.Background()); err != nil {
+			store.logger.WithError(err).Error("Failed to refresh database, keeping last good copy")
+		}
+	}()
+}
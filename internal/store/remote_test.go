@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-marketplace/internal/model"
+	"github.com/mattermost/mattermost-marketplace/internal/testlib"
+)
+
+// remoteDatabase is a fake remote serving a mutable database with ETag and Last-Modified validators.
+type remoteDatabase struct {
+	lock         sync.Mutex
+	statusCode   int
+	body         string
+	etag         string
+	lastModified string
+	requests     []*http.Request
+}
+
+func (rd *remoteDatabase) set(statusCode int, body, etag, lastModified string) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	rd.statusCode = statusCode
+	rd.body = body
+	rd.etag = etag
+	rd.lastModified = lastModified
+}
+
+func (rd *remoteDatabase) lastRequest() *http.Request {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	return rd.requests[len(rd.requests)-1]
+}
+
+func (rd *remoteDatabase) requestCount() int {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	return len(rd.requests)
+}
+
+func (rd *remoteDatabase) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	rd.requests = append(rd.requests, r)
+
+	if rd.etag != "" && r.Header.Get("If-None-Match") == rd.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if rd.etag != "" {
+		w.Header().Set("ETag", rd.etag)
+	}
+	if rd.lastModified != "" {
+		w.Header().Set("Last-Modified", rd.lastModified)
+	}
+	w.WriteHeader(rd.statusCode)
+	_, _ = w.Write([]byte(rd.body))
+}
+
+func TestRemote(t *testing.T) {
+	databaseV1 := `[{"homepage_url":"https://github.com/mattermost/mattermost-plugin-demo","download_url":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz","signature":"signature1","manifest":{"id":"mattermost-plugin-demo","name":"mattermost-plugin-demo","version":"0.1.0"}}]`
+	databaseV2 := `[{"homepage_url":"https://github.com/mattermost/mattermost-plugin-demo","download_url":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.2.0/com.mattermost.demo-plugin-0.2.0.tar.gz","signature":"signature1","manifest":{"id":"mattermost-plugin-demo","name":"mattermost-plugin-demo","version":"0.2.0"}}]`
+
+	getVersion := func(t *testing.T, store *Remote) string {
+		t.Helper()
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{PerPage: model.AllPerPage})
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+
+		return plugins[0].Manifest.Version
+	}
+
+	setup := func(t *testing.T) (*remoteDatabase, *Remote) {
+		t.Helper()
+
+		logger := testlib.MakeLogger(t)
+		remote := &remoteDatabase{}
+		remote.set(http.StatusOK, databaseV1, `"v1"`, "Mon, 19 Oct 2026 10:00:00 GMT")
+
+		ts := httptest.NewServer(remote)
+		t.Cleanup(ts.Close)
+
+		store, err := NewRemote(ts.URL, logger)
+		require.NoError(t, err)
+		require.Equal(t, ts.URL, store.Source())
+
+		return remote, store
+	}
+
+	t.Run("initial fetch fails", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		remote := &remoteDatabase{}
+		remote.set(http.StatusNotFound, "", "", "")
+
+		ts := httptest.NewServer(remote)
+		t.Cleanup(ts.Close)
+
+		store, err := NewRemote(ts.URL, logger)
+		require.Error(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("initial database is invalid", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		remote := &remoteDatabase{}
+		remote.set(http.StatusOK, `{"invalid":`, "", "")
+
+		ts := httptest.NewServer(remote)
+		t.Cleanup(ts.Close)
+
+		store, err := NewRemote(ts.URL, logger)
+		require.Error(t, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("initial fetch", func(t *testing.T) {
+		_, store := setup(t)
+
+		assert.Equal(t, "0.1.0", getVersion(t, store))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		remote, store := setup(t)
+
+		require.NoError(t, store.Refresh(context.Background()))
+		assert.Equal(t, `"v1"`, remote.lastRequest().Header.Get("If-None-Match"))
+		assert.Equal(t, "Mon, 19 Oct 2026 10:00:00 GMT", remote.lastRequest().Header.Get("If-Modified-Since"))
+		assert.Equal(t, "0.1.0", getVersion(t, store))
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		remote, store := setup(t)
+
+		remote.set(http.StatusOK, databaseV2, `"v2"`, "")
+		require.NoError(t, store.Refresh(context.Background()))
+		assert.Equal(t, "0.2.0", getVersion(t, store))
+
+		require.NoError(t, store.Refresh(context.Background()))
+		assert.Equal(t, `"v2"`, remote.lastRequest().Header.Get("If-None-Match"))
+		assert.Empty(t, remote.lastRequest().Header.Get("If-Modified-Since"))
+	})
+
+	t.Run("keeps last good copy on server error", func(t *testing.T) {
+		remote, store := setup(t)
+
+		remote.set(http.StatusInternalServerError, "", "", "")
+		require.Error(t, store.Refresh(context.Background()))
+		assert.Equal(t, "0.1.0", getVersion(t, store))
+	})
+
+	t.Run("keeps last good copy on invalid database", func(t *testing.T) {
+		remote, store := setup(t)
+
+		remote.set(http.StatusOK, `[{"manifest":{"id":"missing-version"}}]`, `"v3"`, "")
+		require.Error(t, store.Refresh(context.Background()))
+		assert.Equal(t, "0.1.0", getVersion(t, store))
+
+		// The validators of the invalid copy must not be remembered.
+		remote.set(http.StatusOK, databaseV2, `"v3"`, "")
+		require.NoError(t, store.Refresh(context.Background()))
+		assert.Equal(t, "0.2.0", getVersion(t, store))
+	})
+
+	t.Run("refresh interval", func(t *testing.T) {
+		remote, store := setup(t)
+		store.SetRefreshInterval(time.Hour)
+
+		remote.set(http.StatusOK, databaseV2, `"v2"`, "")
+		assert.Equal(t, "0.1.0", getVersion(t, store))
+		assert.Equal(t, 1, remote.requestCount())
+
+		// Pretend the last check was long enough ago. The refresh happens in the background, with
+		// the last good copy served in the meantime.
+		store.lastChecked.Store(time.Now().Add(-time.Hour).UnixNano())
+		store.refreshIfStale()
+		assert.Eventually(t, func() bool {
+			return getVersion(t, store) == "0.2.0"
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 2, remote.requestCount())
+
+		assert.Equal(t, "0.2.0", getVersion(t, store))
+		assert.Equal(t, 2, remote.requestCount())
+	})
+
+	t.Run("source omits credentials", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		remote := &remoteDatabase{}
+		remote.set(http.StatusOK, databaseV1, `"v1"`, "")
+
+		ts := httptest.NewServer(remote)
+		t.Cleanup(ts.Close)
+
+		databaseURL := strings.Replace(ts.URL, "http://", "http://user:secret@", 1) + "/plugins.json?X-Amz-Signature=secret#fragment"
+		store, err := NewRemote(databaseURL, logger)
+		require.NoError(t, err)
+		assert.Equal(t, ts.URL+"/plugins.json", store.Source())
+
+		plugins, err := store.GetPlugins(&model.PluginFilter{PerPage: model.AllPerPage})
+		require.NoError(t, err)
+		require.Len(t, plugins, 1)
+		assert.Equal(t, "X-Amz-Signature=secret", remote.lastRequest().URL.RawQuery)
+	})
+
+	t.Run("poll", func(t *testing.T) {
+		remote, store := setup(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			store.Poll(ctx, 10*time.Millisecond)
+			close(done)
+		}()
+
+		remote.set(http.StatusOK, databaseV2, `"v2"`, "")
+		assert.Eventually(t, func() bool {
+			plugins, err := store.GetPlugins(&model.PluginFilter{PerPage: model.AllPerPage})
+			return err == nil && len(plugins) == 1 && plugins[0].Manifest.Version == "0.2.0"
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		<-done
+	})
+}
@@ -17,10 +17,12 @@ var minVersionSupportingEnterpriseFlags = semver.MustParse("5.25.0")
 // StaticStore provides access to a store backed by a static set of plugins.
 type StaticStore struct {
 	plugins []*model.Plugin
+	pins    VersionPins
+	source  string
 	logger  logrus.FieldLogger
 }
 
-// NewStatic constructs a new instance of a static store, parsing the plugins from the given reader.
+// NewStaticFromReader constructs a new instance of a static store, parsing the plugins from the given reader.
 func NewStaticFromReader(reader io.Reader, logger logrus.FieldLogger) (*StaticStore, error) {
 	plugins, err := model.PluginsFromReader(reader)
 	if err != nil {
@@ -30,18 +32,49 @@ func NewStaticFromReader(reader io.Reader, logger logrus.FieldLogger) (*StaticSt
 	return NewStatic(plugins, logger)
 }
 
+// NewStaticFromValidatedReader constructs a new instance of a static store, parsing the plugins
+// from the given reader without validating them.
+//
+// It is intended for databases already validated at build time, e.g. by `generator catalog`,
+// avoiding the cost of validation on startup.
+func NewStaticFromValidatedReader(reader io.Reader, logger logrus.FieldLogger) (*StaticStore, error) {
+	plugins, err := model.PluginsFromReader(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse stream")
+	}
+
+	return &StaticStore{
+		plugins: plugins,
+		logger:  logger,
+	}, nil
+}
+
 // NewStatic constructs a new instance of a static store using the given plugins.
 func NewStatic(plugins []*model.Plugin, logger logrus.FieldLogger) (*StaticStore, error) {
 	if err := validatePlugins(plugins, logger); err != nil {
 		return nil, errors.Wrap(err, "failed to validate plugins")
 	}
 
+	if err := validateUniqueReleases(plugins); err != nil {
+		return nil, errors.Wrap(err, "failed to validate plugins")
+	}
+
 	return &StaticStore{
-		plugins,
-		logger,
+		plugins: plugins,
+		logger:  logger,
 	}, nil
 }
 
+// SetSource configures the description of where the plugins originate from, e.g. a file path.
+func (store *StaticStore) SetSource(source string) {
+	store.source = source
+}
+
+// Source describes where the plugins originate from.
+func (store *StaticStore) Source() string {
+	return store.source
+}
+
 func validatePlugins(plugins []*model.Plugin, logger logrus.FieldLogger) error {
 	for _, plugin := range plugins {
 		err := plugin.Manifest.IsValid()
@@ -60,6 +93,31 @@ func validatePlugins(plugins []*model.Plugin, logger logrus.FieldLogger) error {
 	return nil
 }
 
+// validateUniqueReleases checks that no two plugins share an id and version or a download URL,
+// since the store would otherwise silently resolve them by list order.
+func validateUniqueReleases(plugins []*model.Plugin) error {
+	releases := make(map[string]bool, len(plugins))
+	downloadURLs := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		release := plugin.Manifest.Id + "@" + plugin.Manifest.Version
+		if releases[release] {
+			return errors.Errorf("duplicate release %s of plugin %s", plugin.Manifest.Version, plugin.Manifest.Id)
+		}
+		releases[release] = true
+
+		if plugin.DownloadURL == "" {
+			continue
+		}
+
+		if downloadURLs[plugin.DownloadURL] {
+			return errors.Errorf("duplicate download url %s for release %s of plugin %s", plugin.DownloadURL, plugin.Manifest.Version, plugin.Manifest.Id)
+		}
+		downloadURLs[plugin.DownloadURL] = true
+	}
+
+	return nil
+}
+
 func pluginMatchesFilter(plugin *model.Plugin, filter string) bool {
 	filter = strings.ToLower(filter)
 	if strings.ToLower(plugin.Manifest.Id) == filter {
@@ -89,7 +147,7 @@ func (store *StaticStore) GetPlugins(pluginFilter *model.PluginFilter) ([]*model
 	}
 
 	if !pluginFilter.ReturnAllVersions {
-		plugins, err = filterToLatestVersion(plugins)
+		plugins, err = filterToLatestVersion(plugins, store.pins)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to filter to latest version")
 		}
@@ -139,36 +197,57 @@ func (store *StaticStore) GetPlugins(pluginFilter *model.PluginFilter) ([]*model
 	return plugins[start:end], nil
 }
 
-func filterToLatestVersion(plugins []*model.Plugin) ([]*model.Plugin, error) {
+func filterToLatestVersion(plugins []*model.Plugin, pins VersionPins) ([]*model.Plugin, error) {
 	latestVersionCollector := make(map[string]*model.Plugin)
+	pinnedVersionCollector := make(map[string]*model.Plugin)
 	for _, plugin := range plugins {
-		if latestVersionCollector[plugin.Manifest.Id] == nil {
-			latestVersionCollector[plugin.Manifest.Id] = plugin
-			continue
-		}
-
-		lastSeenPluginVersion, err := semver.Parse(latestVersionCollector[plugin.Manifest.Id].Manifest.Version)
+		storePluginVersion, err := semver.Parse(plugin.Manifest.Version)
 		if err != nil {
 			return nil, errors.Errorf("failed to parse manifest.Version for manifest.Id %s", plugin.Manifest.Id)
 		}
 
-		storePluginVersion := semver.MustParse(plugin.Manifest.Version)
-
 		// Replace the existing plugin if this version is newer, or if it's the same but
 		// appears later in the list.
-		if storePluginVersion.GTE(lastSeenPluginVersion) {
+		if isNewerOrSame(storePluginVersion, latestVersionCollector[plugin.Manifest.Id]) {
 			latestVersionCollector[plugin.Manifest.Id] = plugin
 		}
+
+		// Track the newest version satisfying the pin separately, if the plugin is pinned.
+		versionRange, ok := pins[plugin.Manifest.Id]
+		if ok && versionRange(storePluginVersion) && isNewerOrSame(storePluginVersion, pinnedVersionCollector[plugin.Manifest.Id]) {
+			pinnedVersionCollector[plugin.Manifest.Id] = plugin
+		}
 	}
 
 	result := make([]*model.Plugin, 0, len(plugins))
-	for _, plugin := range latestVersionCollector {
+	for pluginID, plugin := range latestVersionCollector {
+		// Prefer the pinned version, if any, labelling it only if it differs from the latest
+		// version. A pin that no version satisfies is ignored rather than hiding the plugin
+		// altogether.
+		if pinnedPlugin := pinnedVersionCollector[pluginID]; pinnedPlugin != nil && pinnedPlugin != plugin {
+			plugin = pinnedPlugin
+
+			labels := make([]model.Label, 0, len(plugin.Labels)+1)
+			labels = append(labels, plugin.Labels...)
+			plugin.Labels = append(labels, model.PinnedLabel)
+		}
+
 		result = append(result, plugin)
 	}
 
 	return result, nil
 }
 
+// isNewerOrSame returns true if the given version is the same or newer than that of the given
+// plugin, or if there is no such plugin.
+func isNewerOrSame(version semver.Version, plugin *model.Plugin) bool {
+	if plugin == nil {
+		return true
+	}
+
+	return version.GTE(semver.MustParse(plugin.Manifest.Version))
+}
+
 // getPlugins returns all plugins compatible with the given server version, sorted by name ascending.
 func (store *StaticStore) getPlugins(serverVersion string, includeEnterprisePlugins bool, isCloud bool, platform string) ([]*model.Plugin, error) {
 	var result []*model.Plugin
@@ -236,6 +315,8 @@ func (store *StaticStore) getPlugins(serverVersion string, includeEnterprisePlug
 			if bundle.DownloadURL != "" && bundle.Signature != "" {
 				storePlugin.DownloadURL = bundle.DownloadURL
 				storePlugin.Signature = bundle.Signature
+				storePlugin.SHA256 = bundle.SHA256
+				storePlugin.Size = bundle.Size
 			}
 		}
 
@@ -72,6 +72,50 @@ func TestNewStatic(t *testing.T) {
 		assert.Nil(t, store)
 	})
 
+	t.Run("duplicate release", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		store, err := NewStatic([]*model.Plugin{
+			{
+				DownloadURL: "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz",
+				Manifest: &mattermostModel.Manifest{
+					Id:      "com.mattermost.demo-plugin",
+					Version: "0.1.0",
+				},
+			},
+			{
+				DownloadURL: "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.1/com.mattermost.demo-plugin-0.1.0.tar.gz",
+				Manifest: &mattermostModel.Manifest{
+					Id:      "com.mattermost.demo-plugin",
+					Version: "0.1.0",
+				},
+			},
+		}, logger)
+		assert.EqualError(t, err, "failed to validate plugins: duplicate release 0.1.0 of plugin com.mattermost.demo-plugin")
+		assert.Nil(t, store)
+	})
+
+	t.Run("duplicate download url", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		store, err := NewStatic([]*model.Plugin{
+			{
+				DownloadURL: "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz",
+				Manifest: &mattermostModel.Manifest{
+					Id:      "com.mattermost.demo-plugin",
+					Version: "0.1.0",
+				},
+			},
+			{
+				DownloadURL: "https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz",
+				Manifest: &mattermostModel.Manifest{
+					Id:      "com.mattermost.demo-plugin",
+					Version: "0.2.0",
+				},
+			},
+		}, logger)
+		assert.EqualError(t, err, "failed to validate plugins: duplicate download url https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz for release 0.2.0 of plugin com.mattermost.demo-plugin")
+		assert.Nil(t, store)
+	})
+
 	t.Run("missing min_server_version version is valid", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
 		store, err := NewStatic([]*model.Plugin{
@@ -93,8 +137,8 @@ func TestNewStatic(t *testing.T) {
 				Signature:       "signature2",
 				ReleaseNotesURL: "https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0",
 				Manifest: &mattermostModel.Manifest{
-					Id:      "test",
-					Name:    "Test",
+					Id:      "test2",
+					Name:    "Test 2",
 					Version: "0.1.0",
 				},
 			},
@@ -125,8 +169,8 @@ func TestNewStatic(t *testing.T) {
 				Signature:       "signature2",
 				ReleaseNotesURL: "https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0",
 				Manifest: &mattermostModel.Manifest{
-					Id:               "test",
-					Name:             "Test",
+					Id:               "test2",
+					Name:             "Test 2",
 					Version:          "0.1.0",
 					MinServerVersion: "5.23.0",
 				},
@@ -169,14 +213,14 @@ func TestNewStaticFromReader(t *testing.T) {
 
 	t.Run("missing min_server_version version is valid", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
-		store, err := NewStaticFromReader(bytes.NewReader([]byte(`[{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-demo","IconData":"icon-data.svg","DownloadURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz","Signature":"c2lnbmF0dXJl","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0"}},{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-starter-template","DownloadURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/download/v0.1.0/com.mattermost.plugin-starter-template-0.1.0.tar.gz","Signature":"signature2","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0"}}]`)), logger)
+		store, err := NewStaticFromReader(bytes.NewReader([]byte(`[{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-demo","IconData":"icon-data.svg","DownloadURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz","Signature":"c2lnbmF0dXJl","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0"}},{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-starter-template","DownloadURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/download/v0.1.0/com.mattermost.plugin-starter-template-0.1.0.tar.gz","Signature":"signature2","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0","Manifest":{"id": "test2", "name": "Test 2", "version": "0.1.0"}}]`)), logger)
 		assert.NoError(t, err)
 		assert.NotNil(t, store)
 	})
 
 	t.Run("valid stream", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
-		store, err := NewStaticFromReader(bytes.NewReader([]byte(`[{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-demo","IconData":"icon-data.svg","DownloadURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz","Signature":"c2lnbmF0dXJl","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0", "min_server_version":"5.23.0"}},{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-starter-template","DownloadURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/download/v0.1.0/com.mattermost.plugin-starter-template-0.1.0.tar.gz","Signature":"signature2","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0", "min_server_version":"5.23.0"}}]`)), logger)
+		store, err := NewStaticFromReader(bytes.NewReader([]byte(`[{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-demo","IconData":"icon-data.svg","DownloadURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/download/v0.1.0/com.mattermost.demo-plugin-0.1.0.tar.gz","Signature":"c2lnbmF0dXJl","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-demo/releases/v0.1.0","Manifest":{"id": "test", "name": "Test", "version": "0.1.0", "min_server_version":"5.23.0"}},{"HomepageURL":"https://github.com/mattermost/mattermost-plugin-starter-template","DownloadURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/download/v0.1.0/com.mattermost.plugin-starter-template-0.1.0.tar.gz","Signature":"signature2","ReleaseNotesURL":"https://github.com/mattermost/mattermost-plugin-starter-template/releases/v0.1.0","Manifest":{"id": "test2", "name": "Test 2", "version": "0.1.0", "min_server_version":"5.23.0"}}]`)), logger)
 		assert.NoError(t, err)
 		assert.NotNil(t, store)
 	})
@@ -211,21 +255,6 @@ func TestStaticGetPlugins(t *testing.T) {
 		Signature: "signature1",
 	}
 
-	// earlier will never appear, since later instance with same version overrides
-	starterPluginV1Min515Earlier := &model.Plugin{
-		HomepageURL: "https://github.com/mattermost/mattermost-plugin-starter-template-earlier",
-		IconData:    "icon-data2-earlier.svg",
-		DownloadURL: "https://github.com/mattermost/mattermost-plugin-starter-template-earlier/releases/download/v0.1.0/com.mattermost.plugin-starter-template-0.1.0.tar.gz",
-		Manifest: &mattermostModel.Manifest{
-			Id:               "com.mattermost.plugin-starter-template",
-			Name:             "Plugin Starter Template (Earlier)",
-			Description:      "This plugin serves as a starting point for writing a Mattermost plugin.",
-			Version:          "0.1.0",
-			MinServerVersion: "5.15.0",
-		},
-		Signature: "signature2-earlier",
-	}
-
 	starterPluginV1Min515 := &model.Plugin{
 		HomepageURL: "https://github.com/mattermost/mattermost-plugin-starter-template",
 		IconData:    "icon-data2.svg",
@@ -243,7 +272,6 @@ func TestStaticGetPlugins(t *testing.T) {
 	data, err := json.Marshal([]*model.Plugin{
 		demoPluginV1Min514,
 		demoPluginV2Min515,
-		starterPluginV1Min515Earlier,
 		starterPluginV1Min515,
 	})
 	require.NoError(t, err)
@@ -6,3 +6,26 @@ import "github.com/mattermost/mattermost-marketplace/internal/model"
 type Store interface {
 	GetPlugins(filter *model.PluginFilter) ([]*model.Plugin, error)
 }
+
+// Sourcer is implemented by stores able to describe where their plugins originate from, e.g. the
+// path to a database file or the URL of an upstream marketplace.
+type Sourcer interface {
+	Source() string
+}
+
+// Named wraps the given store, describing it by the given name rather than where its plugins
+// originate from, e.g. to avoid exposing the path to a local database file.
+func Named(store Store, name string) Store {
+	return &namedStore{Store: store, name: name}
+}
+
+// namedStore is a store described by a configured name.
+type namedStore struct {
+	Store
+	name string
+}
+
+// Source returns the configured name of the store.
+func (store *namedStore) Source() string {
+	return store.name
+}